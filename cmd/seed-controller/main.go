@@ -71,22 +71,23 @@ type TaskSeed struct {
 }
 
 type TaskSeedSpec struct {
-	Size           *int             `json:"size,omitempty"`
-	Seed           *int64           `json:"seed,omitempty"`
-	Mode           string           `json:"mode,omitempty"`
-	SeedVersion    string           `json:"seedVersion,omitempty"`
-	TitlePrefix    string           `json:"titlePrefix,omitempty"`
-	Tags           []string         `json:"tags,omitempty"`
-	DoneRatio      *float64         `json:"doneRatio,omitempty"`
-	TagCountMin    *int             `json:"tagCountMin,omitempty"`
-	TagCountMax    *int             `json:"tagCountMax,omitempty"`
-	CreatedAtStart string           `json:"createdAtStart,omitempty"`
-	CreatedAtEnd   string           `json:"createdAtEnd,omitempty"`
-	Database       string           `json:"database,omitempty"`
-	Collection     string           `json:"collection,omitempty"`
-	MongoDB        TaskSeedMongoDB  `json:"mongodb,omitempty"`
-	Job            TaskSeedJobSpec  `json:"job,omitempty"`
-	MaintenanceSchedule string      `json:"maintenanceSchedule,omitempty"`
+	Size                *int            `json:"size,omitempty"`
+	Seed                *int64          `json:"seed,omitempty"`
+	Mode                string          `json:"mode,omitempty"`
+	SeedVersion         string          `json:"seedVersion,omitempty"`
+	TitlePrefix         string          `json:"titlePrefix,omitempty"`
+	Tags                []string        `json:"tags,omitempty"`
+	DoneRatio           *float64        `json:"doneRatio,omitempty"`
+	PriorityWeights     []float64       `json:"priorityWeights,omitempty"`
+	TagCountMin         *int            `json:"tagCountMin,omitempty"`
+	TagCountMax         *int            `json:"tagCountMax,omitempty"`
+	CreatedAtStart      string          `json:"createdAtStart,omitempty"`
+	CreatedAtEnd        string          `json:"createdAtEnd,omitempty"`
+	Database            string          `json:"database,omitempty"`
+	Collection          string          `json:"collection,omitempty"`
+	MongoDB             TaskSeedMongoDB `json:"mongodb,omitempty"`
+	Job                 TaskSeedJobSpec `json:"job,omitempty"`
+	MaintenanceSchedule string          `json:"maintenanceSchedule,omitempty"`
 }
 
 type TaskSeedMongoDB struct {
@@ -115,21 +116,22 @@ type TaskSeedStatus struct {
 }
 
 type SeedInput struct {
-	Size           int       `json:"size"`
-	RandomSeed     int64     `json:"seed"`
-	Mode           string    `json:"mode"`
-	SeedVersion    string    `json:"seedVersion,omitempty"`
-	TitlePrefix    string    `json:"titlePrefix,omitempty"`
-	Tags           []string  `json:"tags,omitempty"`
-	DoneRatio      float64   `json:"doneRatio"`
-	TagCountMin    int       `json:"tagCountMin"`
-	TagCountMax    int       `json:"tagCountMax"`
-	CreatedAtStart string    `json:"createdAtStart,omitempty"`
-	CreatedAtEnd   string    `json:"createdAtEnd,omitempty"`
-	Database       string    `json:"database"`
-	Collection     string    `json:"collection"`
-	MongoURI       string    `json:"mongoUri,omitempty"`
-	MongoURISecret *SecretRef `json:"mongoUriSecretRef,omitempty"`
+	Size            int        `json:"size"`
+	RandomSeed      int64      `json:"seed"`
+	Mode            string     `json:"mode"`
+	SeedVersion     string     `json:"seedVersion,omitempty"`
+	TitlePrefix     string     `json:"titlePrefix,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	DoneRatio       float64    `json:"doneRatio"`
+	PriorityWeights []float64  `json:"priorityWeights,omitempty"`
+	TagCountMin     int        `json:"tagCountMin"`
+	TagCountMax     int        `json:"tagCountMax"`
+	CreatedAtStart  string     `json:"createdAtStart,omitempty"`
+	CreatedAtEnd    string     `json:"createdAtEnd,omitempty"`
+	Database        string     `json:"database"`
+	Collection      string     `json:"collection"`
+	MongoURI        string     `json:"mongoUri,omitempty"`
+	MongoURISecret  *SecretRef `json:"mongoUriSecretRef,omitempty"`
 }
 
 func main() {
@@ -397,6 +399,17 @@ func resolveSeedInput(cfg ControllerConfig, spec TaskSeedSpec) (SeedInput, error
 		return SeedInput{}, errors.New("spec.doneRatio must be between 0 and 1")
 	}
 
+	if len(spec.PriorityWeights) > 0 {
+		if len(spec.PriorityWeights) != 4 {
+			return SeedInput{}, errors.New("spec.priorityWeights must have 4 values (low, normal, high, urgent)")
+		}
+		for _, weight := range spec.PriorityWeights {
+			if weight < 0 {
+				return SeedInput{}, errors.New("spec.priorityWeights must be non-negative")
+			}
+		}
+	}
+
 	tagMin := cfg.DefaultSeedTagCountMin
 	if spec.TagCountMin != nil {
 		tagMin = *spec.TagCountMin
@@ -440,21 +453,22 @@ func resolveSeedInput(cfg ControllerConfig, spec TaskSeedSpec) (SeedInput, error
 	}
 
 	return SeedInput{
-		Size:           size,
-		RandomSeed:     randomSeed,
-		Mode:           mode,
-		SeedVersion:    spec.SeedVersion,
-		TitlePrefix:    titlePrefix,
-		Tags:           tags,
-		DoneRatio:      doneRatio,
-		TagCountMin:    tagMin,
-		TagCountMax:    tagMax,
-		CreatedAtStart: spec.CreatedAtStart,
-		CreatedAtEnd:   spec.CreatedAtEnd,
-		Database:       dbName,
-		Collection:     collection,
-		MongoURI:       mongoURI,
-		MongoURISecret: mongoSecret,
+		Size:            size,
+		RandomSeed:      randomSeed,
+		Mode:            mode,
+		SeedVersion:     spec.SeedVersion,
+		TitlePrefix:     titlePrefix,
+		Tags:            tags,
+		DoneRatio:       doneRatio,
+		PriorityWeights: spec.PriorityWeights,
+		TagCountMin:     tagMin,
+		TagCountMax:     tagMax,
+		CreatedAtStart:  spec.CreatedAtStart,
+		CreatedAtEnd:    spec.CreatedAtEnd,
+		Database:        dbName,
+		Collection:      collection,
+		MongoURI:        mongoURI,
+		MongoURISecret:  mongoSecret,
 	}, nil
 }
 
@@ -628,6 +642,13 @@ func buildEnv(input SeedInput, mode string) []corev1.EnvVar {
 	if len(input.Tags) > 0 {
 		env = append(env, corev1.EnvVar{Name: "SEED_TAGS", Value: strings.Join(input.Tags, ",")})
 	}
+	if len(input.PriorityWeights) > 0 {
+		weights := make([]string, 0, len(input.PriorityWeights))
+		for _, weight := range input.PriorityWeights {
+			weights = append(weights, fmt.Sprintf("%g", weight))
+		}
+		env = append(env, corev1.EnvVar{Name: "SEED_PRIORITY_WEIGHTS", Value: strings.Join(weights, ",")})
+	}
 	if input.CreatedAtStart != "" {
 		env = append(env, corev1.EnvVar{Name: "SEED_CREATED_AT_START", Value: input.CreatedAtStart})
 	}
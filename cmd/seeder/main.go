@@ -77,6 +77,14 @@ func loadConfig() (AppConfig, error) {
 	if err != nil {
 		return AppConfig{}, fmt.Errorf("invalid SEED_DONE_RATIO: %w", err)
 	}
+	dueRatio, err := config.FloatEnv("SEED_DUE_RATIO", 0.6)
+	if err != nil {
+		return AppConfig{}, fmt.Errorf("invalid SEED_DUE_RATIO: %w", err)
+	}
+	priorityWeights, err := config.FloatListEnv("SEED_PRIORITY_WEIGHTS", "1,6,2,1")
+	if err != nil {
+		return AppConfig{}, fmt.Errorf("invalid SEED_PRIORITY_WEIGHTS: %w", err)
+	}
 	tagMin, err := config.IntEnv("SEED_TAG_COUNT_MIN", 0)
 	if err != nil {
 		return AppConfig{}, fmt.Errorf("invalid SEED_TAG_COUNT_MIN: %w", err)
@@ -100,18 +108,20 @@ func loadConfig() (AppConfig, error) {
 		MongoCollection: config.GetEnv("MONGODB_COLLECTION", defaultMongoCollection),
 		Timeout:         timeout,
 		Seed: seed.Config{
-			Count:          count,
-			RandomSeed:     randomSeed,
-			Mode:           seed.Mode(config.GetEnv("SEED_MODE", string(seed.ModeUpsert))),
-			SeedVersion:    config.GetEnv("SEED_VERSION", ""),
-			TitlePrefix:    config.GetEnv("SEED_TITLE_PREFIX", "Task"),
-			Tags:           config.SplitCommaList(config.GetEnv("SEED_TAGS", "demo,seed")),
-			DoneRatio:      doneRatio,
-			TagCountMin:    tagMin,
-			TagCountMax:    tagMax,
-			CreatedAtStart: createdAtStart,
-			CreatedAtEnd:   createdAtEnd,
-			Timeout:        timeout,
+			Count:           count,
+			RandomSeed:      randomSeed,
+			Mode:            seed.Mode(config.GetEnv("SEED_MODE", string(seed.ModeUpsert))),
+			SeedVersion:     config.GetEnv("SEED_VERSION", ""),
+			TitlePrefix:     config.GetEnv("SEED_TITLE_PREFIX", "Task"),
+			Tags:            config.SplitCommaList(config.GetEnv("SEED_TAGS", "demo,seed")),
+			DoneRatio:       doneRatio,
+			DueRatio:        dueRatio,
+			PriorityWeights: priorityWeights,
+			TagCountMin:     tagMin,
+			TagCountMax:     tagMax,
+			CreatedAtStart:  createdAtStart,
+			CreatedAtEnd:    createdAtEnd,
+			Timeout:         timeout,
 		},
 	}, nil
 }
@@ -19,22 +19,48 @@ import (
 	"task-api-huma-mongo/internal/config"
 	"task-api-huma-mongo/internal/service"
 	"task-api-huma-mongo/internal/store"
+	"task-api-huma-mongo/internal/webhook"
 )
 
 const (
-	defaultPort            = 8080
-	defaultMongoURI        = "mongodb://localhost:27017"
-	defaultMongoDB         = "taskdb"
-	defaultMongoCollection = "tasks"
-	defaultDBTimeout       = 5 * time.Second
+	defaultPort                  = 8080
+	defaultMongoURI              = "mongodb://localhost:27017"
+	defaultMongoDB               = "taskdb"
+	defaultMongoCollection       = "tasks"
+	defaultProjectsCollection    = "projects"
+	defaultRevisionsCollection   = "task_revisions"
+	defaultIdempotencyCollection = "idempotency_keys"
+	defaultWebhooksCollection    = "webhooks"
+	defaultDeliveriesCollection  = "webhook_deliveries"
+	defaultCountersCollection    = "counters"
+	defaultIdempotencyTTL        = 24 * time.Hour
+	defaultDBTimeout             = 5 * time.Second
+	defaultTrashRetention        = 30 * 24 * time.Hour
+	defaultDeliveryRetention     = 30 * 24 * time.Hour
+	defaultWebhookWorkers        = 4
+	defaultWebhookPollInterval   = 2 * time.Second
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookMaxAttempts    = 8
+	defaultWebhookBaseBackoff    = 30 * time.Second
+	defaultWebhookMaxBackoff     = time.Hour
 )
 
 type Config struct {
-	Port             int
-	MongoURI         string
-	MongoDB          string
-	MongoCollection  string
-	CORSAllowOrigins []string
+	Port                  int
+	MongoURI              string
+	MongoDB               string
+	MongoCollection       string
+	ProjectsCollection    string
+	RevisionsCollection   string
+	IdempotencyCollection string
+	IdempotencyTTL        time.Duration
+	WebhooksCollection    string
+	DeliveriesCollection  string
+	CountersCollection    string
+	Webhooks              webhook.Config
+	CORSAllowOrigins      []string
+	TrashRetention        time.Duration
+	DeliveryRetention     time.Duration
 }
 
 func main() {
@@ -57,18 +83,60 @@ func main() {
 		_ = mongoStore.Disconnect(context.Background())
 	}()
 
-	repo := store.NewMongoTaskRepository(mongoStore)
-	svc := service.New(repo)
+	repo := store.NewMongoTaskRepository(mongoStore, cfg.CountersCollection)
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	if err := repo.EnsureTrashRetention(ctx, cfg.TrashRetention); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	projects := store.NewMongoProjectRepository(mongoStore, cfg.ProjectsCollection)
+	if err := projects.EnsureIndexes(ctx); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	revisions := store.NewMongoRevisionRepository(mongoStore, cfg.RevisionsCollection)
+	if err := revisions.EnsureIndexes(ctx); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	idempotency := store.NewMongoIdempotencyRepository(mongoStore, cfg.IdempotencyCollection)
+	if err := idempotency.EnsureIndexes(ctx, cfg.IdempotencyTTL); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	webhooks := store.NewMongoWebhookRepository(mongoStore, cfg.WebhooksCollection, cfg.DeliveriesCollection)
+	if err := webhooks.EnsureIndexes(ctx); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	if err := webhooks.EnsureDeliveryRetention(ctx, cfg.DeliveryRetention); err != nil {
+		slog.Error("mongo index error", "err", err)
+		os.Exit(1)
+	}
+	svc := service.New(repo, projects, revisions, idempotency, webhooks)
+
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		webhook.NewDispatcher(svc, cfg.Webhooks).Run(dispatchCtx)
+	}()
 
 	mux := http.NewServeMux()
 	api.InstallErrorHandler()
 
-	humaAPI := humago.New(mux, huma.DefaultConfig("Task API", "1.0.0"))
+	humaAPI := humago.New(api.NewCustomMethodMux(mux), huma.DefaultConfig("Task API", "1.0.0"))
 	api.RegisterRoutes(humaAPI, svc)
+	api.RegisterCalDAVRoutes(mux, svc)
 
 	handler := api.RequestLoggingMiddleware(
 		api.CorrelationMiddleware(
-			api.CORSMiddleware(cfg.CORSAllowOrigins)(mux),
+			api.ActorMiddleware(
+				api.CORSMiddleware(cfg.CORSAllowOrigins)(mux),
+			),
 		),
 	)
 
@@ -98,6 +166,8 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		slog.Error("shutdown error", "err", err)
 	}
+	stopDispatch()
+	<-dispatched
 }
 
 func loadConfig() (Config, error) {
@@ -107,11 +177,82 @@ func loadConfig() (Config, error) {
 		return Config{}, fmt.Errorf("invalid PORT: %s", portValue)
 	}
 
+	trashRetention, err := config.DurationEnv("TRASH_RETENTION", defaultTrashRetention)
+	if err != nil || trashRetention < 0 {
+		return Config{}, fmt.Errorf("invalid TRASH_RETENTION: %s", config.GetEnv("TRASH_RETENTION", ""))
+	}
+
+	deliveryRetention, err := config.DurationEnv("WEBHOOK_DELIVERY_RETENTION", defaultDeliveryRetention)
+	if err != nil || deliveryRetention < 0 {
+		return Config{}, fmt.Errorf("invalid WEBHOOK_DELIVERY_RETENTION: %s", config.GetEnv("WEBHOOK_DELIVERY_RETENTION", ""))
+	}
+
+	idempotencyTTL, err := config.DurationEnv("IDEMPOTENCY_TTL", defaultIdempotencyTTL)
+	if err != nil || idempotencyTTL <= 0 {
+		return Config{}, fmt.Errorf("invalid IDEMPOTENCY_TTL: %s", config.GetEnv("IDEMPOTENCY_TTL", ""))
+	}
+
+	webhookConfig, err := loadWebhookConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
-		Port:             port,
-		MongoURI:         config.GetEnv("MONGODB_URI", defaultMongoURI),
-		MongoDB:          config.GetEnv("MONGODB_DB", defaultMongoDB),
-		MongoCollection:  config.GetEnv("MONGODB_COLLECTION", defaultMongoCollection),
-		CORSAllowOrigins: config.SplitCommaList(config.GetEnv("CORS_ALLOW_ORIGINS", "http://localhost:8081,http://127.0.0.1:8081")),
+		Port:                  port,
+		MongoURI:              config.GetEnv("MONGODB_URI", defaultMongoURI),
+		MongoDB:               config.GetEnv("MONGODB_DB", defaultMongoDB),
+		MongoCollection:       config.GetEnv("MONGODB_COLLECTION", defaultMongoCollection),
+		ProjectsCollection:    config.GetEnv("MONGODB_PROJECTS_COLLECTION", defaultProjectsCollection),
+		RevisionsCollection:   config.GetEnv("MONGODB_REVISIONS_COLLECTION", defaultRevisionsCollection),
+		IdempotencyCollection: config.GetEnv("MONGODB_IDEMPOTENCY_COLLECTION", defaultIdempotencyCollection),
+		IdempotencyTTL:        idempotencyTTL,
+		WebhooksCollection:    config.GetEnv("MONGODB_WEBHOOKS_COLLECTION", defaultWebhooksCollection),
+		DeliveriesCollection:  config.GetEnv("MONGODB_DELIVERIES_COLLECTION", defaultDeliveriesCollection),
+		CountersCollection:    config.GetEnv("MONGODB_COUNTERS_COLLECTION", defaultCountersCollection),
+		Webhooks:              webhookConfig,
+		CORSAllowOrigins:      config.SplitCommaList(config.GetEnv("CORS_ALLOW_ORIGINS", "http://localhost:8081,http://127.0.0.1:8081")),
+		TrashRetention:        trashRetention,
+		DeliveryRetention:     deliveryRetention,
+	}, nil
+}
+
+func loadWebhookConfig() (webhook.Config, error) {
+	workers, err := config.IntEnv("WEBHOOK_WORKERS", defaultWebhookWorkers)
+	if err != nil || workers <= 0 {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_WORKERS: %s", config.GetEnv("WEBHOOK_WORKERS", ""))
+	}
+	pollInterval, err := config.DurationEnv("WEBHOOK_POLL_INTERVAL", defaultWebhookPollInterval)
+	if err != nil || pollInterval <= 0 {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_POLL_INTERVAL: %s", config.GetEnv("WEBHOOK_POLL_INTERVAL", ""))
+	}
+	timeout, err := config.DurationEnv("WEBHOOK_TIMEOUT", defaultWebhookTimeout)
+	if err != nil || timeout <= 0 {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_TIMEOUT: %s", config.GetEnv("WEBHOOK_TIMEOUT", ""))
+	}
+	maxAttempts, err := config.IntEnv("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts)
+	if err != nil || maxAttempts <= 0 {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %s", config.GetEnv("WEBHOOK_MAX_ATTEMPTS", ""))
+	}
+	baseBackoff, err := config.DurationEnv("WEBHOOK_BACKOFF_BASE", defaultWebhookBaseBackoff)
+	if err != nil || baseBackoff <= 0 {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_BACKOFF_BASE: %s", config.GetEnv("WEBHOOK_BACKOFF_BASE", ""))
+	}
+	maxBackoff, err := config.DurationEnv("WEBHOOK_BACKOFF_MAX", defaultWebhookMaxBackoff)
+	if err != nil || maxBackoff < baseBackoff {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_BACKOFF_MAX: %s", config.GetEnv("WEBHOOK_BACKOFF_MAX", ""))
+	}
+	allowPrivate, err := strconv.ParseBool(config.GetEnv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "false"))
+	if err != nil {
+		return webhook.Config{}, fmt.Errorf("invalid WEBHOOK_ALLOW_PRIVATE_TARGETS: %s", config.GetEnv("WEBHOOK_ALLOW_PRIVATE_TARGETS", ""))
+	}
+
+	return webhook.Config{
+		Workers:             workers,
+		PollInterval:        pollInterval,
+		Timeout:             timeout,
+		MaxAttempts:         maxAttempts,
+		BaseBackoff:         baseBackoff,
+		MaxBackoff:          maxBackoff,
+		AllowPrivateTargets: allowPrivate,
 	}, nil
 }
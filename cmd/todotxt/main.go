@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"task-api-huma-mongo/internal/config"
+	"task-api-huma-mongo/internal/service"
+	"task-api-huma-mongo/internal/store"
+	"task-api-huma-mongo/internal/todotxt"
+)
+
+const (
+	defaultMongoURI              = "mongodb://localhost:27017"
+	defaultMongoDB               = "taskdb"
+	defaultMongoCollection       = "tasks"
+	defaultProjectsCollection    = "projects"
+	defaultRevisionsCollection   = "task_revisions"
+	defaultIdempotencyCollection = "idempotency_keys"
+	defaultWebhooksCollection    = "webhooks"
+	defaultDeliveriesCollection  = "webhook_deliveries"
+	defaultCountersCollection    = "counters"
+	defaultDBTimeout             = 5 * time.Second
+)
+
+const usage = `usage:
+  todotxt export [-tag tag] [-project id] [-done true|false] > todo.txt
+  todotxt import [-dry-run] [todo.txt]`
+
+type AppConfig struct {
+	MongoURI              string
+	MongoDB               string
+	MongoCollection       string
+	ProjectsCollection    string
+	RevisionsCollection   string
+	IdempotencyCollection string
+	WebhooksCollection    string
+	DeliveriesCollection  string
+	CountersCollection    string
+	Timeout               time.Duration
+}
+
+// main exports tasks to, or imports them from, a todo.txt file, going
+// through the service like the API does, so imports are validated the
+// same way and emit the same webhook events.
+func main() {
+	// Standard output carries the exported file, so logs go to standard
+	// error.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		slog.Error("config error", "err", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	mongoStore, err := store.NewMongoStore(ctx, cfg.MongoURI, cfg.MongoDB, cfg.MongoCollection, cfg.Timeout)
+	if err != nil {
+		slog.Error("mongo connect error", "err", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = mongoStore.Disconnect(context.Background())
+	}()
+
+	svc := service.New(
+		store.NewMongoTaskRepository(mongoStore, cfg.CountersCollection),
+		store.NewMongoProjectRepository(mongoStore, cfg.ProjectsCollection),
+		store.NewMongoRevisionRepository(mongoStore, cfg.RevisionsCollection),
+		store.NewMongoIdempotencyRepository(mongoStore, cfg.IdempotencyCollection),
+		store.NewMongoWebhookRepository(mongoStore, cfg.WebhooksCollection, cfg.DeliveriesCollection),
+	)
+
+	switch os.Args[1] {
+	case "export":
+		err = runExport(ctx, svc, os.Args[2:])
+	case "import":
+		err = runImport(ctx, svc, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error(os.Args[1]+" failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func runExport(ctx context.Context, svc *service.Service, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	tag := flags.String("tag", "", "only tasks with this tag")
+	projectID := flags.String("project", "", "only tasks in this project")
+	done := flags.String("done", "", "only done (true) or open (false) tasks")
+	_ = flags.Parse(args)
+
+	filter := service.TaskFilter{Tag: *tag, ProjectID: *projectID}
+	if *done != "" {
+		value, err := strconv.ParseBool(*done)
+		if err != nil {
+			return fmt.Errorf("invalid -done: %s", *done)
+		}
+		filter.Done = &value
+	}
+
+	cursor, err := svc.Export(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	out := bufio.NewWriter(os.Stdout)
+	w := todotxt.NewWriter(out)
+	var exported int
+	for cursor.Next(ctx) {
+		if err := w.Write(todotxt.FromTask(cursor.Task())); err != nil {
+			return err
+		}
+		exported++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	slog.Info("export completed", "exported", exported)
+	return nil
+}
+
+func runImport(ctx context.Context, svc *service.Service, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "validate the lines without inserting them")
+	_ = flags.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	lines, err := todotxt.Decode(in)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rows     []service.ImportTask
+		rowLine  []int
+		accepted int
+		rejected int
+	)
+	for n, line := range lines {
+		row, err := line.ImportTask()
+		if err != nil {
+			slog.Warn("task rejected", "line", n+1, "err", err)
+			rejected++
+			continue
+		}
+		rows = append(rows, row)
+		rowLine = append(rowLine, n)
+	}
+
+	for start := 0; start < len(rows); start += service.MaxImportRows {
+		end := min(start+service.MaxImportRows, len(rows))
+		results, err := svc.Import(ctx, rows[start:end], *dryRun)
+		if err != nil {
+			return err
+		}
+		for j, result := range results {
+			if result.Err != nil {
+				slog.Warn("task rejected", "line", rowLine[start+j]+1, "err", result.Err)
+				rejected++
+				continue
+			}
+			accepted++
+		}
+	}
+
+	slog.Info("import completed", "accepted", accepted, "rejected", rejected, "dry_run", *dryRun)
+	return nil
+}
+
+func loadConfig() (AppConfig, error) {
+	timeout, err := config.DurationEnv("TODOTXT_TIMEOUT", defaultDBTimeout)
+	if err != nil || timeout <= 0 {
+		return AppConfig{}, fmt.Errorf("invalid TODOTXT_TIMEOUT: %s", config.GetEnv("TODOTXT_TIMEOUT", ""))
+	}
+
+	return AppConfig{
+		MongoURI:              config.GetEnv("MONGODB_URI", defaultMongoURI),
+		MongoDB:               config.GetEnv("MONGODB_DB", defaultMongoDB),
+		MongoCollection:       config.GetEnv("MONGODB_COLLECTION", defaultMongoCollection),
+		ProjectsCollection:    config.GetEnv("MONGODB_PROJECTS_COLLECTION", defaultProjectsCollection),
+		RevisionsCollection:   config.GetEnv("MONGODB_REVISIONS_COLLECTION", defaultRevisionsCollection),
+		IdempotencyCollection: config.GetEnv("MONGODB_IDEMPOTENCY_COLLECTION", defaultIdempotencyCollection),
+		WebhooksCollection:    config.GetEnv("MONGODB_WEBHOOKS_COLLECTION", defaultWebhooksCollection),
+		DeliveriesCollection:  config.GetEnv("MONGODB_DELIVERIES_COLLECTION", defaultDeliveriesCollection),
+		CountersCollection:    config.GetEnv("MONGODB_COUNTERS_COLLECTION", defaultCountersCollection),
+		Timeout:               timeout,
+	}, nil
+}
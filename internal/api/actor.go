@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// ActorHeader names who makes a request. The API has no authentication,
+// so it is taken at face value and only recorded on task revisions.
+const ActorHeader = "X-Actor"
+
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := r.Header.Get(ActorHeader); actor != "" {
+			r = r.WithContext(service.WithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
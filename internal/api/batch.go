@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type BatchInput struct {
+	Body BatchBody
+}
+
+type BatchBody struct {
+	Atomic     bool                 `json:"atomic,omitempty" doc:"Apply every operation or none, in a transaction"`
+	Operations []BatchOperationBody `json:"operations" minItems:"1" maxItems:"500"`
+}
+
+type BatchOperationBody struct {
+	Op      string          `json:"op" enum:"create,update,delete"`
+	ID      string          `json:"id,omitempty" doc:"Task to update or delete"`
+	IfMatch string          `json:"ifMatch,omitempty" doc:"ETag the task must still have, like the If-Match header"`
+	Task    *CreateTaskBody `json:"task,omitempty" doc:"Task to create"`
+	Patch   *UpdateTaskBody `json:"patch,omitempty" doc:"Fields to update"`
+}
+
+type BatchOutput struct {
+	Body BatchResponse
+}
+
+type BatchResponse struct {
+	Results   []BatchResultBody `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+type BatchResultBody struct {
+	Index  int           `json:"index"`
+	Status int           `json:"status"`
+	Task   *service.Task `json:"task,omitempty"`
+	Error  *APIError     `json:"error,omitempty"`
+}
+
+func (i *BatchInput) Resolve(ctx huma.Context) []error {
+	var errs []error
+	for n, op := range i.Body.Operations {
+		location := fmt.Sprintf("body.operations[%d]", n)
+		switch {
+		case op.Op == string(service.BatchCreate) && op.Task == nil:
+			errs = append(errs, &huma.ErrorDetail{Message: "task is required for create", Location: location + ".task"})
+		case op.Op == string(service.BatchUpdate) && op.Patch == nil:
+			errs = append(errs, &huma.ErrorDetail{Message: "patch is required for update", Location: location + ".patch"})
+		case op.Op != string(service.BatchCreate) && op.ID == "":
+			errs = append(errs, &huma.ErrorDetail{Message: "id is required for " + op.Op, Location: location + ".id"})
+		}
+	}
+	return errs
+}
+
+func (b BatchOperationBody) operation() service.BatchOperation {
+	op := service.BatchOperation{Kind: service.BatchOpKind(b.Op), ID: b.ID}
+	switch {
+	case b.Task != nil:
+		op.Create = createTaskRequest(*b.Task)
+	case b.Patch != nil:
+		op.Update = updateTaskRequest(*b.Patch)
+		op.Update.IfVersion = ifMatchVersion(b.IfMatch)
+	}
+	if op.Kind == service.BatchDelete {
+		op.IfVersion = ifMatchVersion(b.IfMatch)
+	}
+	return op
+}
+
+func registerBatchRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "batch-tasks",
+		Method:      http.MethodPost,
+		Path:        "/tasks:batch",
+		Summary:     "Create, update and delete tasks in one request",
+		Description: "Every operation gets its own result with the status it would have had as a single request. " +
+			"With atomic set, either every operation is applied or none is.",
+	}, func(ctx context.Context, input *BatchInput) (*BatchOutput, error) {
+		ops := make([]service.BatchOperation, 0, len(input.Body.Operations))
+		for _, op := range input.Body.Operations {
+			ops = append(ops, op.operation())
+		}
+		results, err := svc.Batch(ctx, ops, input.Body.Atomic)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+
+		resp := BatchResponse{Results: make([]BatchResultBody, 0, len(results))}
+		for i, result := range results {
+			body := BatchResultBody{Index: i, Task: result.Task}
+			if result.Err != nil {
+				var apiErr *APIError
+				if !errors.As(MapServiceError(ctx, result.Err), &apiErr) {
+					return nil, MapServiceError(ctx, result.Err)
+				}
+				body.Status = apiErr.Status
+				body.Error = apiErr
+				resp.Failed++
+			} else {
+				body.Status = batchSuccessStatus(ops[i].Kind)
+				resp.Succeeded++
+			}
+			resp.Results = append(resp.Results, body)
+		}
+		return &BatchOutput{Body: resp}, nil
+	})
+}
+
+func batchSuccessStatus(kind service.BatchOpKind) int {
+	switch kind {
+	case service.BatchCreate:
+		return http.StatusCreated
+	case service.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
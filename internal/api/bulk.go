@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// BulkParams guard the endpoints that change every task matching a filter.
+type BulkParams struct {
+	Confirm OptionalParam[int64] `query:"confirm" doc:"Number of tasks the filter is expected to match; required unless dryRun is set"`
+	DryRun  bool                 `query:"dryRun" doc:"Only count the matching tasks"`
+}
+
+type UpdateWhereInput struct {
+	TaskFilterParams
+	BulkParams
+	Body UpdateTaskBody
+}
+
+type DeleteWhereInput struct {
+	TaskFilterParams
+	BulkParams
+}
+
+type BulkOutput struct {
+	Body BulkResponse
+}
+
+type BulkResponse struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+	DryRun   bool  `json:"dryRun,omitempty"`
+}
+
+func (i *UpdateWhereInput) Resolve(ctx huma.Context) []error {
+	return i.Body.resolve()
+}
+
+func newBulkOutput(result *service.BulkResult) *BulkOutput {
+	return &BulkOutput{Body: BulkResponse{
+		Matched:  result.Matched,
+		Modified: result.Modified,
+		DryRun:   result.DryRun,
+	}}
+}
+
+func registerBulkRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-tasks-where",
+		Method:      http.MethodPatch,
+		Path:        "/tasks",
+		Summary:     "Update every task matching a filter",
+		Description: "Takes the same filters as list-tasks. Pass dryRun to count the matching tasks, " +
+			"then repeat the request with confirm set to that count.",
+	}, func(ctx context.Context, input *UpdateWhereInput) (*BulkOutput, error) {
+		result, err := svc.UpdateWhere(ctx, input.Filter(), updateTaskRequest(input.Body), input.Confirm.Ptr(), input.DryRun)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newBulkOutput(result), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-tasks-where",
+		Method:      http.MethodDelete,
+		Path:        "/tasks",
+		Summary:     "Move every task matching a filter to the trash",
+		Description: "Takes the same filters as list-tasks. Pass dryRun to count the matching tasks, " +
+			"then repeat the request with confirm set to that count.",
+	}, func(ctx context.Context, input *DeleteWhereInput) (*BulkOutput, error) {
+		result, err := svc.DeleteWhere(ctx, input.Filter(), input.Confirm.Ptr(), input.DryRun)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newBulkOutput(result), nil
+	})
+}
@@ -0,0 +1,663 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/ical"
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	calDAVRoot         = "/caldav/"
+	calDAVCollection   = "/caldav/tasks/"
+	calDAVMaxBodyBytes = 1024 * 1024
+	calDAVDisplayName  = "Tasks"
+	calDAVMethods      = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+	calDAVContentType  = ical.ContentType + "; charset=utf-8; component=VTODO"
+
+	davNS            = "DAV:"
+	calDAVNS         = "urn:ietf:params:xml:ns:caldav"
+	calendarServerNS = "http://calendarserver.org/ns/"
+)
+
+var (
+	propResourceType       = xml.Name{Space: davNS, Local: "resourcetype"}
+	propDisplayName        = xml.Name{Space: davNS, Local: "displayname"}
+	propPrincipal          = xml.Name{Space: davNS, Local: "current-user-principal"}
+	propPrincipalURL       = xml.Name{Space: davNS, Local: "principal-URL"}
+	propPrivileges         = xml.Name{Space: davNS, Local: "current-user-privilege-set"}
+	propSupportedReports   = xml.Name{Space: davNS, Local: "supported-report-set"}
+	propETag               = xml.Name{Space: davNS, Local: "getetag"}
+	propContentType        = xml.Name{Space: davNS, Local: "getcontenttype"}
+	propCalendarHome       = xml.Name{Space: calDAVNS, Local: "calendar-home-set"}
+	propSupportedComponent = xml.Name{Space: calDAVNS, Local: "supported-calendar-component-set"}
+	propCalendarData       = xml.Name{Space: calDAVNS, Local: "calendar-data"}
+	propCTag               = xml.Name{Space: calendarServerNS, Local: "getctag"}
+)
+
+// RegisterCalDAVRoutes serves the tasks as a CalDAV calendar of VTODOs.
+// The root is both the principal and its calendar home, /caldav/tasks/ is
+// the calendar and /caldav/tasks/{uid}.ics holds each task. This covers
+// what clients need to discover the calendar, sync it by ctag and etag and
+// write tasks back; writes go through the service like the JSON API.
+func RegisterCalDAVRoutes(mux *http.ServeMux, svc *service.Service) {
+	mux.Handle("/.well-known/caldav", http.RedirectHandler(calDAVRoot, http.StatusMovedPermanently))
+	mux.Handle(calDAVRoot, &calDAVHandler{svc: svc})
+}
+
+type calDAVHandler struct {
+	svc *service.Service
+}
+
+func (h *calDAVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", calDAVMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == calDAVRoot:
+		h.serveRoot(w, r)
+	case path+"/" == calDAVCollection || path == calDAVCollection:
+		h.serveCollection(w, r)
+	case strings.HasPrefix(path, calDAVCollection):
+		uid, ok := strings.CutSuffix(path[len(calDAVCollection):], ".ics")
+		if !ok || uid == "" || strings.Contains(uid, "/") {
+			writeCalDAVError(w, r, service.ErrNotFound)
+			return
+		}
+		h.serveTask(w, r, uid)
+	default:
+		writeCalDAVError(w, r, service.ErrNotFound)
+	}
+}
+
+func (h *calDAVHandler) serveRoot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" {
+		methodNotAllowed(w, r)
+		return
+	}
+	req, err := readPropfind(w, r)
+	if err != nil {
+		writeCalDAVError(w, r, err)
+		return
+	}
+	var collection davProps
+	if depth(r) > 0 {
+		if collection, err = h.collectionProps(r.Context()); err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+	}
+
+	ms := newMultistatus(w)
+	ms.response(calDAVRoot, rootProps(), req)
+	if collection != nil {
+		ms.response(calDAVCollection, collection, req)
+	}
+	ms.close()
+}
+
+func (h *calDAVHandler) serveCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PROPFIND":
+		req, err := readPropfind(w, r)
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		props, err := h.collectionProps(r.Context())
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		if depth(r) == 0 {
+			ms := newMultistatus(w)
+			ms.response(calDAVCollection, props, req)
+			ms.close()
+			return
+		}
+		h.listTasks(w, r, service.TaskFilter{}, req, func(ms *multistatus) {
+			ms.response(calDAVCollection, props, req)
+		})
+
+	case "REPORT":
+		h.report(w, r)
+
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// report answers calendar-query, which lists the collection, and
+// calendar-multiget, which fetches the resources it names.
+func (h *calDAVHandler) report(w http.ResponseWriter, r *http.Request) {
+	var body reportBody
+	if err := readXML(w, r, &body); err != nil {
+		writeCalDAVError(w, r, err)
+		return
+	}
+	req := propRequest{all: body.Prop == nil}
+	if body.Prop != nil {
+		req.names = *body.Prop
+	}
+
+	switch body.XMLName {
+	case xml.Name{Space: calDAVNS, Local: "calendar-query"}:
+		filter, ok := body.Filter.taskFilter()
+		if !ok {
+			ms := newMultistatus(w)
+			ms.close()
+			return
+		}
+		h.listTasks(w, r, filter, req, nil)
+
+	case xml.Name{Space: calDAVNS, Local: "calendar-multiget"}:
+		stamp := time.Now().UTC()
+		type result struct {
+			href string
+			task *service.Task
+		}
+		results := make([]result, 0, len(body.Hrefs))
+		for _, href := range body.Hrefs {
+			res := result{href: href}
+			if uid, ok := hrefUID(href); ok {
+				task, err := h.svc.TaskByUID(r.Context(), uid)
+				if err != nil && !errors.Is(err, service.ErrNotFound) {
+					writeCalDAVError(w, r, err)
+					return
+				}
+				res.task = task
+			}
+			results = append(results, res)
+		}
+
+		ms := newMultistatus(w)
+		for _, res := range results {
+			if res.task == nil {
+				ms.missing(res.href)
+				continue
+			}
+			ms.response(res.href, taskProps(*res.task, stamp, req), req)
+		}
+		ms.close()
+
+	default:
+		writeCalDAVError(w, r, NewAPIError(http.StatusForbidden, "forbidden", "unsupported report "+body.XMLName.Local, CorrelationIDFromContext(r.Context()), nil))
+	}
+}
+
+// listTasks answers with one response per task matching filter, after the
+// responses written by head. Like streamTasks it flushes every
+// exportFlushRows tasks and gives the client exportWriteWindow to read each
+// chunk. When the cursor fails partway the connection is dropped instead
+// of closing the listing, which clients would take for the whole
+// collection and delete the tasks it misses.
+func (h *calDAVHandler) listTasks(w http.ResponseWriter, r *http.Request, filter service.TaskFilter, req propRequest, head func(ms *multistatus)) {
+	ctx := r.Context()
+	cursor, err := h.svc.Export(ctx, filter)
+	if err != nil {
+		writeCalDAVError(w, r, err)
+		return
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+	stamp := time.Now().UTC()
+	ms := newMultistatus(w)
+	if head != nil {
+		head(ms)
+	}
+	for rows := 1; cursor.Next(ctx); rows++ {
+		task := cursor.Task()
+		ms.response(taskHref(task), taskProps(task, stamp, req), req)
+		if rows%exportFlushRows == 0 {
+			_ = ms.w.Flush()
+			_ = rc.Flush()
+			_ = rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		slog.Error("caldav listing failed", "err", err, "correlation_id", CorrelationIDFromContext(ctx))
+		panic(http.ErrAbortHandler)
+	}
+	ms.close()
+}
+
+func (h *calDAVHandler) serveTask(w http.ResponseWriter, r *http.Request, uid string) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		task, err := h.svc.TaskByUID(ctx, uid)
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		etag := versionETag(task.Version)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", calDAVContentType)
+		_, _ = io.WriteString(w, taskICS(*task, time.Now().UTC()))
+
+	case http.MethodPut:
+		h.putTask(w, r, uid)
+
+	case http.MethodDelete:
+		task, err := h.svc.TaskByUID(ctx, uid)
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		if err := h.svc.Delete(ctx, task.ID, ifMatchVersion(r.Header.Get("If-Match"))); err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case "PROPFIND":
+		req, err := readPropfind(w, r)
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		task, err := h.svc.TaskByUID(ctx, uid)
+		if err != nil {
+			writeCalDAVError(w, r, err)
+			return
+		}
+		ms := newMultistatus(w)
+		ms.response(taskHref(*task), taskProps(*task, time.Now().UTC(), req), req)
+		ms.close()
+
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// putTask creates the task uid or replaces it, honouring If-Match and
+// If-None-Match: * so clients do not overwrite changes they have not seen.
+func (h *calDAVHandler) putTask(w http.ResponseWriter, r *http.Request, uid string) {
+	ctx := r.Context()
+	correlationID := CorrelationIDFromContext(ctx)
+	if header := r.Header.Get("Content-Type"); header != "" {
+		if mediaType, _, err := mime.ParseMediaType(header); err != nil || mediaType != ical.ContentType {
+			writeCalDAVError(w, r, NewAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be "+ical.ContentType, correlationID, nil))
+			return
+		}
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, calDAVMaxBodyBytes))
+	if err != nil {
+		writeCalDAVError(w, r, bodyReadError(ctx, err))
+		return
+	}
+
+	todos, errs, err := ical.Decode(bytes.NewReader(body))
+	if err != nil {
+		writeCalDAVError(w, r, NewAPIError(http.StatusBadRequest, "bad_request", err.Error(), correlationID, nil))
+		return
+	}
+	if len(todos) != 1 {
+		writeCalDAVError(w, r, NewAPIError(http.StatusForbidden, "forbidden", "a task resource must hold exactly one VTODO", correlationID, nil))
+		return
+	}
+	todo := todos[0]
+	if err := errs[0]; err != nil {
+		invalid := []InvalidParam{{Name: "body", Reason: err.Error()}}
+		var propErr *ical.PropertyError
+		if errors.As(err, &propErr) {
+			invalid = []InvalidParam{{Name: propErr.Property, Reason: propErr.Err.Error()}}
+		}
+		writeCalDAVError(w, r, NewAPIError(http.StatusBadRequest, "bad_request", err.Error(), correlationID, invalid))
+		return
+	}
+	if todo.UID == "" {
+		todo.UID = uid
+	}
+	if todo.UID != uid {
+		invalid := []InvalidParam{{Name: "UID", Reason: "must match the resource name"}}
+		writeCalDAVError(w, r, NewAPIError(http.StatusBadRequest, "bad_request", "UID must match the resource name", correlationID, invalid))
+		return
+	}
+	req := todoImportTask(todo).CreateTaskRequest
+
+	current, err := h.svc.TaskByUID(ctx, uid)
+	if err != nil && !errors.Is(err, service.ErrNotFound) {
+		writeCalDAVError(w, r, err)
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if (current == nil && ifMatch != "") || (current != nil && r.Header.Get("If-None-Match") == "*") {
+		writeCalDAVError(w, r, NewAPIError(http.StatusPreconditionFailed, "precondition_failed", "the resource does not match the request preconditions", correlationID, nil))
+		return
+	}
+
+	status := http.StatusNoContent
+	var task *service.Task
+	if current == nil {
+		task, err = h.svc.Create(ctx, req)
+		status = http.StatusCreated
+	} else {
+		task, err = h.svc.ReplaceTask(ctx, current.ID, req, ifMatchVersion(ifMatch))
+	}
+	if err != nil {
+		writeCalDAVError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", versionETag(task.Version))
+	w.WriteHeader(status)
+}
+
+func (h *calDAVHandler) collectionProps(ctx context.Context) (davProps, error) {
+	ctag, err := h.svc.CalendarTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	props := rootProps()
+	props[propResourceType] = "<D:collection/><C:calendar/>"
+	props[propCTag] = xmlText(ctag)
+	props[propSupportedComponent] = `<C:comp name="VTODO"/>`
+	props[propSupportedReports] = "<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>" +
+		"<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>"
+	return props, nil
+}
+
+func rootProps() davProps {
+	root := "<D:href>" + xmlText(calDAVRoot) + "</D:href>"
+	return davProps{
+		propResourceType: "<D:collection/>",
+		propDisplayName:  xmlText(calDAVDisplayName),
+		propPrincipal:    root,
+		propPrincipalURL: root,
+		propCalendarHome: root,
+		propPrivileges: "<D:privilege><D:all/></D:privilege><D:privilege><D:read/></D:privilege>" +
+			"<D:privilege><D:write/></D:privilege>",
+	}
+}
+
+// taskProps returns the properties of a task resource. The calendar data
+// is only rendered when req asks for it.
+func taskProps(task service.Task, stamp time.Time, req propRequest) davProps {
+	props := davProps{
+		propResourceType: "",
+		propETag:         xmlText(versionETag(task.Version)),
+		propContentType:  xmlText(calDAVContentType),
+	}
+	if slices.Contains(req.names, propCalendarData) {
+		props[propCalendarData] = xmlText(taskICS(task, stamp))
+	}
+	return props
+}
+
+func taskICS(task service.Task, stamp time.Time) string {
+	var b strings.Builder
+	w := ical.NewWriter(&b, icsProdID)
+	_ = w.WriteTodo(taskTodo(task, stamp))
+	_ = w.Close()
+	return b.String()
+}
+
+func taskHref(task service.Task) string {
+	return calDAVCollection + url.PathEscape(service.CalendarUID(task)) + ".ics"
+}
+
+// hrefUID returns the UID of the task resource href, which may be a path
+// or an absolute URL.
+func hrefUID(href string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(parsed.Path, calDAVCollection)
+	if !ok {
+		return "", false
+	}
+	uid, ok := strings.CutSuffix(name, ".ics")
+	if !ok || uid == "" || strings.Contains(uid, "/") {
+		return "", false
+	}
+	return uid, true
+}
+
+// depth reads the Depth header; anything but 0 lists the members of a
+// collection, since no collection here is nested.
+func depth(r *http.Request) int {
+	if strings.TrimSpace(r.Header.Get("Depth")) == "0" {
+		return 0
+	}
+	return 1
+}
+
+// davProps holds the known properties of a resource as inner XML, which
+// may use the D, C and CS prefixes declared by multistatus.
+type davProps map[xml.Name]string
+
+// propRequest lists the properties a PROPFIND or REPORT asks for; all is
+// set for allprop or an empty body.
+type propRequest struct {
+	all   bool
+	names []xml.Name
+}
+
+// propNames collects the names of the child elements of DAV:prop.
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindBody struct {
+	XMLName xml.Name   `xml:"DAV: propfind"`
+	AllProp *struct{}  `xml:"DAV: allprop"`
+	Prop    *propNames `xml:"DAV: prop"`
+}
+
+type reportBody struct {
+	XMLName xml.Name
+	Prop    *propNames      `xml:"DAV: prop"`
+	Hrefs   []string        `xml:"DAV: href"`
+	Filter  *calendarFilter `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type calendarFilter struct {
+	Comp compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type compFilter struct {
+	Name  string       `xml:"name,attr"`
+	Comps []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	Props []propFilter `xml:"urn:ietf:params:xml:ns:caldav prop-filter"`
+}
+
+type propFilter struct {
+	Name         string    `xml:"name,attr"`
+	IsNotDefined *struct{} `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+}
+
+// taskFilter maps a calendar-query filter onto a task filter; ok is false
+// when the query asks for another component than VTODO. Of the property
+// filters, only COMPLETED is-not-defined, which clients send to hide
+// finished tasks, is applied.
+func (f *calendarFilter) taskFilter() (filter service.TaskFilter, ok bool) {
+	if f == nil || len(f.Comp.Comps) == 0 {
+		return filter, true
+	}
+	for _, comp := range f.Comp.Comps {
+		if !strings.EqualFold(comp.Name, "VTODO") {
+			continue
+		}
+		for _, prop := range comp.Props {
+			if strings.EqualFold(prop.Name, "COMPLETED") && prop.IsNotDefined != nil {
+				open := false
+				filter.Done = &open
+			}
+		}
+		return filter, true
+	}
+	return filter, false
+}
+
+func readPropfind(w http.ResponseWriter, r *http.Request) (propRequest, error) {
+	var body propfindBody
+	if err := readXML(w, r, &body); err != nil {
+		return propRequest{}, err
+	}
+	if body.Prop == nil || body.AllProp != nil {
+		return propRequest{all: true}, nil
+	}
+	return propRequest{names: *body.Prop}, nil
+}
+
+// readXML decodes the request body into v. An empty body leaves v as is.
+func readXML(w http.ResponseWriter, r *http.Request, v any) error {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, calDAVMaxBodyBytes))
+	if err != nil {
+		return bodyReadError(r.Context(), err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		return NewAPIError(http.StatusBadRequest, "bad_request", "malformed XML body: "+err.Error(), CorrelationIDFromContext(r.Context()), nil)
+	}
+	return nil
+}
+
+func bodyReadError(ctx context.Context, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return NewAPIError(http.StatusRequestEntityTooLarge, "error", fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit), CorrelationIDFromContext(ctx), nil)
+	}
+	return NewAPIError(http.StatusBadRequest, "bad_request", "cannot read request body", CorrelationIDFromContext(ctx), nil)
+}
+
+// multistatus streams a 207 Multi-Status body.
+type multistatus struct {
+	w *bufio.Writer
+}
+
+func newMultistatus(w http.ResponseWriter) *multistatus {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	ms := &multistatus{w: bufio.NewWriter(w)}
+	ms.w.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		`<D:multistatus xmlns:D="` + davNS + `" xmlns:C="` + calDAVNS + `" xmlns:CS="` + calendarServerNS + `">`)
+	return ms
+}
+
+// response reports the properties req asks for: those in props with 200,
+// the others with 404.
+func (ms *multistatus) response(href string, props davProps, req propRequest) {
+	var found, missing []xml.Name
+	if req.all {
+		for name := range props {
+			found = append(found, name)
+		}
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].Space+found[i].Local < found[j].Space+found[j].Local
+		})
+	} else {
+		for _, name := range req.names {
+			if _, ok := props[name]; ok {
+				found = append(found, name)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+	}
+
+	ms.w.WriteString("<D:response><D:href>" + xmlText(href) + "</D:href>")
+	ms.propstat(found, props, "200 OK")
+	ms.propstat(missing, props, "404 Not Found")
+	ms.w.WriteString("</D:response>")
+}
+
+func (ms *multistatus) propstat(names []xml.Name, props davProps, status string) {
+	if len(names) == 0 {
+		return
+	}
+	ms.w.WriteString("<D:propstat><D:prop>")
+	for _, name := range names {
+		// Each property declares its own namespace, so that unknown ones
+		// need no prefix.
+		ms.w.WriteString("<" + name.Local + ` xmlns="` + xmlText(name.Space) + `">` + props[name] + "</" + name.Local + ">")
+	}
+	ms.w.WriteString("</D:prop><D:status>HTTP/1.1 " + status + "</D:status></D:propstat>")
+}
+
+func (ms *multistatus) missing(href string) {
+	ms.w.WriteString("<D:response><D:href>" + xmlText(href) + "</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>")
+}
+
+func (ms *multistatus) close() {
+	ms.w.WriteString("</D:multistatus>\n")
+	_ = ms.w.Flush()
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", calDAVMethods)
+	writeCalDAVError(w, r, NewAPIError(http.StatusMethodNotAllowed, "error", "method not allowed", CorrelationIDFromContext(r.Context()), nil))
+}
+
+// writeCalDAVError answers with the same problem body as the JSON API.
+func writeCalDAVError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		err = MapServiceError(r.Context(), err)
+		errors.As(err, &apiErr)
+	}
+	var headersErr huma.HeadersError
+	if errors.As(err, &headersErr) {
+		for name, values := range headersErr.GetHeaders() {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
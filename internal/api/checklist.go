@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type AddChecklistItemInput struct {
+	ID   string `path:"id"`
+	Body AddChecklistItemBody
+}
+
+type AddChecklistItemBody struct {
+	Text     string `json:"text" minLength:"1" maxLength:"500"`
+	Position *int   `json:"position,omitempty" minimum:"0" doc:"Insert position, appends when omitted"`
+}
+
+type ChecklistItemInput struct {
+	ID     string `path:"id"`
+	ItemID string `path:"itemId"`
+}
+
+type ReorderChecklistInput struct {
+	ID   string `path:"id"`
+	Body ReorderChecklistBody
+}
+
+type ReorderChecklistBody struct {
+	ItemIDs []string `json:"itemIds" doc:"Every checklist item ID, in the new order"`
+}
+
+func registerChecklistRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "add-checklist-item",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/checklist",
+		Summary:     "Add checklist item",
+	}, func(ctx context.Context, input *AddChecklistItemInput) (*TaskOutput, error) {
+		task, err := svc.AddChecklistItem(ctx, input.ID, input.Body.Text, input.Body.Position)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "toggle-checklist-item",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/checklist/{itemId}/toggle",
+		Summary:     "Toggle checklist item done flag",
+	}, func(ctx context.Context, input *ChecklistItemInput) (*TaskOutput, error) {
+		task, err := svc.ToggleChecklistItem(ctx, input.ID, input.ItemID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "reorder-checklist",
+		Method:      http.MethodPut,
+		Path:        "/tasks/{id}/checklist/order",
+		Summary:     "Reorder checklist",
+	}, func(ctx context.Context, input *ReorderChecklistInput) (*TaskOutput, error) {
+		task, err := svc.ReorderChecklist(ctx, input.ID, input.Body.ItemIDs)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-checklist-item",
+		Method:      http.MethodDelete,
+		Path:        "/tasks/{id}/checklist/{itemId}",
+		Summary:     "Delete checklist item",
+	}, func(ctx context.Context, input *ChecklistItemInput) (*TaskOutput, error) {
+		task, err := svc.DeleteChecklistItem(ctx, input.ID, input.ItemID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+}
@@ -49,8 +49,9 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 				w.Header().Add("Vary", "Origin")
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PATCH,DELETE,OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-Id")
+			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-Id, X-Actor, If-Match, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-Id")
 			w.Header().Set("Access-Control-Max-Age", "600")
 
 			if r.Method == http.MethodOptions {
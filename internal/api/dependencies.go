@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type DependenciesOutput struct {
+	Body DependenciesResponse
+}
+
+type DependenciesResponse struct {
+	BlockedBy []service.Task `json:"blockedBy"`
+	Open      int            `json:"open" doc:"Number of blockers that are not done"`
+}
+
+type AddDependencyInput struct {
+	ID   string `path:"id"`
+	Body AddDependencyBody
+}
+
+type AddDependencyBody struct {
+	TaskID string `json:"taskId" doc:"ID of the task that blocks this one"`
+}
+
+type DependencyInput struct {
+	ID        string `path:"id"`
+	BlockerID string `path:"blockerId"`
+}
+
+func registerDependencyRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-dependencies",
+		Method:      http.MethodGet,
+		Path:        "/tasks/{id}/dependencies",
+		Summary:     "List tasks blocking a task",
+	}, func(ctx context.Context, input *TaskIDInput) (*DependenciesOutput, error) {
+		blockers, err := svc.Blockers(ctx, input.ID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		open := 0
+		for _, blocker := range blockers {
+			if !blocker.Done {
+				open++
+			}
+		}
+		return &DependenciesOutput{Body: DependenciesResponse{BlockedBy: blockers, Open: open}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "add-dependency",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/dependencies",
+		Summary:     "Mark a task as blocked by another",
+	}, func(ctx context.Context, input *AddDependencyInput) (*TaskOutput, error) {
+		task, err := svc.AddBlocker(ctx, input.ID, input.Body.TaskID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "remove-dependency",
+		Method:      http.MethodDelete,
+		Path:        "/tasks/{id}/dependencies/{blockerId}",
+		Summary:     "Remove a blocked-by link",
+	}, func(ctx context.Context, input *DependencyInput) (*TaskOutput, error) {
+		task, err := svc.RemoveBlocker(ctx, input.ID, input.BlockerID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+}
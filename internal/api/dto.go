@@ -1,6 +1,9 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"reflect"
 	"strings"
 	"time"
@@ -21,6 +24,7 @@ type HealthResponse struct {
 }
 
 type TaskOutput struct {
+	ETag string `header:"ETag"`
 	Body service.Task
 }
 
@@ -29,38 +33,120 @@ type ListTasksOutput struct {
 }
 
 type ListTasksResponse struct {
-	Items []service.Task `json:"items"`
-	Count int            `json:"count"`
+	Items      []service.Task `json:"items"`
+	Count      int            `json:"count"`
+	NextCursor string         `json:"nextCursor,omitempty" doc:"Opaque cursor for the next page; absent on the last page"`
 }
 
 type CreateTaskInput struct {
-	Body CreateTaskBody
+	IdempotencyKey string `header:"Idempotency-Key" maxLength:"255" doc:"Retries with the same key and body return the task created by the first request"`
+	Body           CreateTaskBody
 }
 
-type CreateTaskBody struct {
-	Title string   `json:"title" minLength:"3"`
-	Done  *bool    `json:"done,omitempty"`
-	Tags  []string `json:"tags,omitempty"`
+type CreateTaskOutput struct {
+	ETag               string `header:"ETag"`
+	IdempotentReplayed string `header:"Idempotent-Replayed" doc:"true when the response was replayed for a repeated Idempotency-Key"`
+	Body               service.Task
 }
 
+type CreateTaskBody struct {
+	Title       string     `json:"title" minLength:"3"`
+	Description string     `json:"description,omitempty" maxLength:"20000" doc:"Markdown"`
+	Done        *bool      `json:"done,omitempty"`
+	Priority    string     `json:"priority,omitempty" enum:"low,normal,high,urgent" default:"normal"`
+	ProjectID   string     `json:"projectId,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	RemindAt    *time.Time `json:"remindAt,omitempty" doc:"Must not be after dueAt"`
+}
+
+// UpdateTaskInput takes the update as application/json (UpdateTaskBody),
+// application/merge-patch+json or application/json-patch+json; Resolve
+// decodes RawBody into exactly one of update, merge and patch.
 type UpdateTaskInput struct {
-	ID   string `path:"id"`
-	Body UpdateTaskBody
+	ID      string `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the task as last read; the update fails with 412 if the task changed since"`
+	RawBody []byte
+
+	update *UpdateTaskBody
+	merge  map[string]any
+	patch  []service.PatchOperation
 }
 
 type UpdateTaskBody struct {
-	Title *string   `json:"title,omitempty" minLength:"3"`
-	Done  *bool     `json:"done,omitempty"`
-	Tags  *[]string `json:"tags,omitempty"`
+	Title       *string    `json:"title,omitempty" minLength:"3"`
+	Description *string    `json:"description,omitempty" maxLength:"20000" doc:"Markdown"`
+	Done        *bool      `json:"done,omitempty"`
+	Priority    *string    `json:"priority,omitempty" enum:"low,normal,high,urgent"`
+	ProjectID   *string    `json:"projectId,omitempty" doc:"Empty string removes the task from its project"`
+	Tags        *[]string  `json:"tags,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	RemindAt    *time.Time `json:"remindAt,omitempty" doc:"Must not be after dueAt"`
 }
 
 type TaskIDInput struct {
 	ID string `path:"id"`
 }
 
+type DeleteTaskInput struct {
+	ID      string `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the task as last read; the delete fails with 412 if the task changed since"`
+}
+
+type GetTaskInput struct {
+	ID     string `path:"id"`
+	Render string `query:"render" enum:"html" doc:"Set to html to include the description rendered as sanitized HTML"`
+}
+
+// TaskFilterParams are the task filters shared by every endpoint that
+// selects tasks.
+type TaskFilterParams struct {
+	ProjectID     string                   `query:"projectId"`
+	Done          OptionalParam[bool]      `query:"done"`
+	Priority      []string                 `query:"priority" enum:"low,normal,high,urgent" doc:"Comma-separated priorities to include"`
+	Tag           string                   `query:"tag"`
+	Q             string                   `query:"q" maxLength:"200" doc:"Full-text search over titles and tags, ranked by relevance" example:"deploy review"`
+	DueBefore     OptionalParam[time.Time] `query:"dueBefore" doc:"Only tasks due before this instant"`
+	DueAfter      OptionalParam[time.Time] `query:"dueAfter" doc:"Only tasks due after this instant"`
+	CreatedBefore OptionalParam[time.Time] `query:"createdBefore" doc:"Only tasks created before this instant"`
+	CreatedAfter  OptionalParam[time.Time] `query:"createdAfter" doc:"Only tasks created after this instant"`
+	Overdue       OptionalParam[bool]      `query:"overdue" doc:"Open tasks whose dueAt has passed"`
+	Blocked       OptionalParam[bool]      `query:"blocked" doc:"Tasks with at least one open blocker"`
+}
+
+func (p TaskFilterParams) Filter() service.TaskFilter {
+	priorities := make([]service.Priority, 0, len(p.Priority))
+	for _, value := range p.Priority {
+		priorities = append(priorities, service.Priority(value))
+	}
+	return service.TaskFilter{
+		ProjectID:     strings.TrimSpace(p.ProjectID),
+		Done:          p.Done.Ptr(),
+		Priorities:    priorities,
+		Tag:           strings.TrimSpace(p.Tag),
+		Query:         strings.TrimSpace(p.Q),
+		DueBefore:     p.DueBefore.Ptr(),
+		DueAfter:      p.DueAfter.Ptr(),
+		CreatedBefore: p.CreatedBefore.Ptr(),
+		CreatedAfter:  p.CreatedAfter.Ptr(),
+		Overdue:       p.Overdue.Ptr(),
+		Blocked:       p.Blocked.Ptr(),
+	}
+}
+
 type ListTasksInput struct {
-	Done OptionalParam[bool] `query:"done"`
-	Tag  string              `query:"tag"`
+	TaskFilterParams
+	Sort   string `query:"sort" doc:"Comma-separated sort fields (createdAt, title, done, priority, score), prefix with - for descending" example:"-createdAt,title"`
+	Cursor string `query:"cursor" doc:"Opaque cursor returned as nextCursor by the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"200" default:"50"`
+}
+
+func (i *ListTasksInput) Options() service.ListOptions {
+	return service.ListOptions{
+		Sort:   i.Sort,
+		Cursor: i.Cursor,
+		Limit:  i.Limit,
+	}
 }
 
 type OptionalParam[T any] struct {
@@ -68,6 +154,15 @@ type OptionalParam[T any] struct {
 	IsSet bool
 }
 
+// Ptr returns the parameter value, or nil when it was not provided.
+func (o OptionalParam[T]) Ptr() *T {
+	if !o.IsSet {
+		return nil
+	}
+	value := o.Value
+	return &value
+}
+
 func (o OptionalParam[T]) Schema(r huma.Registry) *huma.Schema {
 	return huma.SchemaFromType(r, reflect.TypeOf(o.Value))
 }
@@ -86,24 +181,37 @@ func (i *CreateTaskInput) Resolve(ctx huma.Context) []error {
 		return []error{&huma.ErrorDetail{Message: "title must be at least 3 characters", Location: "body.title", Value: i.Body.Title}}
 	}
 	i.Body.Title = title
+	if i.Body.DueAt != nil && i.Body.RemindAt != nil && i.Body.RemindAt.After(*i.Body.DueAt) {
+		return []error{&huma.ErrorDetail{Message: "remindAt must not be after dueAt", Location: "body.remindAt", Value: i.Body.RemindAt}}
+	}
 	return nil
 }
 
-func (i *UpdateTaskInput) Resolve(ctx huma.Context) []error {
-	if i.Body.Title != nil {
-		trimmed := strings.TrimSpace(*i.Body.Title)
+// hash identifies the request body for Idempotency-Key checks. It hashes
+// the decoded body, so formatting differences between retries do not count.
+func (b CreateTaskBody) hash() string {
+	payload, _ := json.Marshal(b)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// resolve trims the title and rejects empty updates.
+func (b *UpdateTaskBody) resolve() []error {
+	if b.Title != nil {
+		trimmed := strings.TrimSpace(*b.Title)
 		if len(trimmed) < 3 {
-			return []error{&huma.ErrorDetail{Message: "title must be at least 3 characters", Location: "body.title", Value: *i.Body.Title}}
+			return []error{&huma.ErrorDetail{Message: "title must be at least 3 characters", Location: "body.title", Value: *b.Title}}
 		}
-		i.Body.Title = &trimmed
+		b.Title = &trimmed
 	}
-	if i.Body.Title == nil && i.Body.Done == nil && i.Body.Tags == nil {
+	if b.Title == nil && b.Description == nil && b.Done == nil && b.Priority == nil && b.ProjectID == nil && b.Tags == nil && b.DueAt == nil && b.RemindAt == nil {
 		return []error{&huma.ErrorDetail{Message: "at least one field must be provided", Location: "body"}}
 	}
 	return nil
 }
 
 func (i *ListTasksInput) Resolve(ctx huma.Context) []error {
-	i.Tag = strings.TrimSpace(i.Tag)
+	i.Sort = strings.TrimSpace(i.Sort)
+	i.Cursor = strings.TrimSpace(i.Cursor)
 	return nil
 }
@@ -31,6 +31,8 @@ type APIError struct {
 	Message       string         `json:"message,omitempty"`
 	CorrelationID string         `json:"correlationId,omitempty"`
 	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+	// CurrentVersion is set on 412 responses to failed If-Match checks.
+	CurrentVersion *int64 `json:"currentVersion,omitempty"`
 }
 
 func (e *APIError) Error() string {
@@ -96,13 +98,47 @@ func MapServiceError(ctx context.Context, err error) error {
 	correlationID := CorrelationIDFromContext(ctx)
 
 	var vErr *service.ValidationError
+	var cErr *service.ConflictError
+	var mErr *service.VersionMismatchError
 	switch {
 	case errors.As(err, &vErr):
 		invalid := []InvalidParam{{Name: vErr.Field, Reason: vErr.Message}}
 		return NewAPIError(http.StatusBadRequest, "bad_request", vErr.Message, correlationID, invalid)
+	case errors.As(err, &cErr):
+		return NewAPIError(http.StatusConflict, "conflict", cErr.Message, correlationID, nil)
+	case errors.As(err, &mErr):
+		apiErr := NewAPIError(http.StatusPreconditionFailed, "precondition_failed", mErr.Error(), correlationID, nil)
+		apiErr.CurrentVersion = &mErr.Current
+		return huma.ErrorWithHeaders(apiErr, http.Header{"ETag": {versionETag(mErr.Current)}})
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		invalid := []InvalidParam{{Name: "Idempotency-Key", Reason: "already used with a different request body"}}
+		return NewAPIError(http.StatusUnprocessableEntity, "unprocessable_entity", err.Error(), correlationID, invalid)
+	case errors.Is(err, service.ErrBatchAborted):
+		return NewAPIError(http.StatusFailedDependency, "failed_dependency", err.Error(), correlationID, nil)
 	case errors.Is(err, service.ErrInvalidID):
 		invalid := []InvalidParam{{Name: "id", Reason: "must be a valid ObjectID hex"}}
 		return NewAPIError(http.StatusBadRequest, "bad_request", "invalid task id", correlationID, invalid)
+	case errors.Is(err, service.ErrChecklistItemNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "checklist item not found", correlationID, nil)
+	case errors.Is(err, service.ErrInvalidProjectID):
+		invalid := []InvalidParam{{Name: "projectId", Reason: "must be a valid ObjectID hex"}}
+		return NewAPIError(http.StatusBadRequest, "bad_request", "invalid project id", correlationID, invalid)
+	case errors.Is(err, service.ErrProjectNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "project not found", correlationID, nil)
+	case errors.Is(err, service.ErrRevisionNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "revision not found", correlationID, nil)
+	case errors.Is(err, service.ErrTagNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "tag not found", correlationID, nil)
+	case errors.Is(err, service.ErrInvalidWebhookID):
+		invalid := []InvalidParam{{Name: "id", Reason: "must be a valid ObjectID hex"}}
+		return NewAPIError(http.StatusBadRequest, "bad_request", "invalid webhook id", correlationID, invalid)
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "webhook not found", correlationID, nil)
+	case errors.Is(err, service.ErrInvalidDeliveryID):
+		invalid := []InvalidParam{{Name: "deliveryId", Reason: "must be a valid ObjectID hex"}}
+		return NewAPIError(http.StatusBadRequest, "bad_request", "invalid delivery id", correlationID, invalid)
+	case errors.Is(err, service.ErrDeliveryNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "delivery not found", correlationID, nil)
 	case errors.Is(err, service.ErrNotFound):
 		return NewAPIError(http.StatusNotFound, "not_found", "task not found", correlationID, nil)
 	default:
@@ -160,6 +196,10 @@ func errorCodeFromStatus(status int) string {
 		return "not_found"
 	case http.StatusConflict:
 		return "conflict"
+	case http.StatusPreconditionFailed:
+		return "precondition_failed"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
 	case http.StatusTooManyRequests:
 		return "too_many_requests"
 	case http.StatusServiceUnavailable:
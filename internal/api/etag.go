@@ -0,0 +1,35 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+func newTaskOutput(task *service.Task) *TaskOutput {
+	return &TaskOutput{ETag: versionETag(task.Version), Body: *task}
+}
+
+// versionETag formats a task version as a strong entity tag.
+func versionETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatchVersion converts an If-Match header into the version a write
+// expects to find. It returns nil when the header is missing or "*". Any
+// value that is not a single ETag issued by versionETag, weak tags
+// included, can never match and yields -1, so the write fails with 412.
+func ifMatchVersion(header string) *int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+	version := int64(-1)
+	if unquoted, err := strconv.Unquote(header); err == nil && strings.HasPrefix(header, `"`) {
+		if parsed, err := strconv.ParseInt(unquoted, 10, 64); err == nil && parsed >= 0 {
+			version = parsed
+		}
+	}
+	return &version
+}
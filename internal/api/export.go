@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/ical"
+	"task-api-huma-mongo/internal/service"
+	"task-api-huma-mongo/internal/todotxt"
+)
+
+const (
+	// exportFlushRows is how many tasks are buffered before the response is
+	// flushed to the client.
+	exportFlushRows = 500
+	// exportWriteWindow is how long the client has to read each flushed
+	// chunk; it replaces the server's write timeout for the export.
+	exportWriteWindow = 30 * time.Second
+	// csvTagSeparator joins the tags of a task in a single CSV column.
+	csvTagSeparator = ";"
+)
+
+// taskCSVHeader lists the CSV columns of exports, which imports read back.
+var taskCSVHeader = []string{
+	"id", "title", "description", "done", "priority", "projectId", "tags",
+	"createdAt", "completedAt", "dueAt", "remindAt",
+}
+
+type ExportTasksInput struct {
+	TaskFilterParams
+	Format string `query:"format" enum:"csv,ndjson,json" default:"json"`
+}
+
+// taskWriter encodes a stream of tasks in one export format. Flush hands
+// buffered tasks to the underlying writer; Close also ends the document.
+type taskWriter interface {
+	Write(task service.Task) error
+	Flush() error
+	Close() error
+}
+
+// exportContentTypes is the Content-Type of each format streamTasks
+// writes.
+var exportContentTypes = map[string]string{
+	"csv":     "text/csv; charset=utf-8",
+	"ndjson":  "application/x-ndjson",
+	"json":    "application/json",
+	"ics":     ical.ContentType + "; charset=utf-8",
+	"todotxt": todotxt.ContentType + "; charset=utf-8",
+}
+
+func registerExportRoutes(api huma.API, svc *service.Service) {
+	taskSchema := api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf(service.Task{}), true, "Task")
+	huma.Register(api, huma.Operation{
+		OperationID: "export-tasks",
+		Method:      http.MethodGet,
+		Path:        "/tasks/export",
+		Summary:     "Export tasks",
+		Description: "Streams every task matching the same filters as list-tasks, oldest first, as a download. " +
+			"CSV joins tags with \";\".",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "Exported tasks",
+				Headers: map[string]*huma.Param{
+					"Content-Disposition": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+				Content: map[string]*huma.MediaType{
+					"text/csv":             {Schema: &huma.Schema{Type: huma.TypeString}},
+					"application/x-ndjson": {Schema: taskSchema},
+					"application/json":     {Schema: &huma.Schema{Type: huma.TypeArray, Items: taskSchema}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *ExportTasksInput) (*huma.StreamResponse, error) {
+		cursor, err := svc.Export(ctx, input.Filter())
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return streamExport(ctx, cursor, input.Format, exportFilename("tasks", input.Format)), nil
+	})
+}
+
+// exportFilename names a download made now.
+func exportFilename(prefix, extension string) string {
+	return fmt.Sprintf("%s-%s.%s", prefix, time.Now().UTC().Format("20060102-150405"), extension)
+}
+
+// streamExport answers with every task of cursor, in format, as a download
+// named filename. The cursor is closed once the body is written.
+func streamExport(ctx context.Context, cursor service.TaskCursor, format, filename string) *huma.StreamResponse {
+	return &huma.StreamResponse{Body: func(hctx huma.Context) {
+		defer cursor.Close(context.WithoutCancel(ctx))
+		hctx.SetHeader("Content-Type", exportContentTypes[format])
+		hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := streamTasks(ctx, hctx.BodyWriter(), cursor, format); err != nil {
+			slog.Error("task export failed", "err", err, "correlation_id", CorrelationIDFromContext(ctx))
+		}
+	}}
+}
+
+// streamTasks writes every task of cursor to w, flushing every
+// exportFlushRows tasks so neither side holds more than a chunk.
+func streamTasks(ctx context.Context, w io.Writer, cursor service.TaskCursor, format string) error {
+	var rc *http.ResponseController
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rc = http.NewResponseController(rw)
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+	}
+	buf := bufio.NewWriterSize(w, 64*1024)
+	flush := func() error {
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if rc != nil {
+			_ = rc.Flush()
+			_ = rc.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		}
+		return nil
+	}
+
+	out := newTaskWriter(buf, format)
+	for rows := 1; cursor.Next(ctx); rows++ {
+		if err := out.Write(cursor.Task()); err != nil {
+			return err
+		}
+		if rows%exportFlushRows == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func newTaskWriter(w io.Writer, format string) taskWriter {
+	switch format {
+	case "csv":
+		return newCSVTaskWriter(w)
+	case "ndjson":
+		return &ndjsonTaskWriter{enc: json.NewEncoder(w)}
+	case "ics":
+		return newICSTaskWriter(w)
+	case "todotxt":
+		return newTodoTxtTaskWriter(w)
+	default:
+		return &jsonArrayTaskWriter{w: w, enc: json.NewEncoder(w)}
+	}
+}
+
+type csvTaskWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVTaskWriter(w io.Writer) *csvTaskWriter {
+	return &csvTaskWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvTaskWriter) Write(task service.Task) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(taskCSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.w.Write(taskCSVRecord(task))
+}
+
+func (c *csvTaskWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close writes the header of an empty export and flushes.
+func (c *csvTaskWriter) Close() error {
+	if !c.wroteHeader {
+		if err := c.w.Write(taskCSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.Flush()
+}
+
+func taskCSVRecord(task service.Task) []string {
+	return []string{
+		task.ID,
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Done),
+		string(task.Priority),
+		task.ProjectID,
+		strings.Join(task.Tags, csvTagSeparator),
+		formatCSVTime(&task.CreatedAt),
+		formatCSVTime(task.CompletedAt),
+		formatCSVTime(task.DueAt),
+		formatCSVTime(task.RemindAt),
+	}
+}
+
+func formatCSVTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type ndjsonTaskWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonTaskWriter) Write(task service.Task) error { return n.enc.Encode(task) }
+func (n *ndjsonTaskWriter) Flush() error                  { return nil }
+func (n *ndjsonTaskWriter) Close() error                  { return nil }
+
+// jsonArrayTaskWriter writes the tasks as one JSON array, element by
+// element.
+type jsonArrayTaskWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (j *jsonArrayTaskWriter) Write(task service.Task) error {
+	sep := ","
+	if j.count == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	j.count++
+	return j.enc.Encode(task)
+}
+
+func (j *jsonArrayTaskWriter) Flush() error { return nil }
+
+func (j *jsonArrayTaskWriter) Close() error {
+	end := "]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type ListHistoryInput struct {
+	ID     string `path:"id"`
+	Before int    `query:"before" minimum:"0" doc:"Only revisions older than this one; use the last rev of the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"200" default:"50"`
+}
+
+type HistoryOutput struct {
+	Body HistoryResponse
+}
+
+type HistoryResponse struct {
+	Items []service.Revision `json:"items"`
+	Count int                `json:"count"`
+}
+
+type RevisionInput struct {
+	ID  string `path:"id"`
+	Rev int    `path:"rev" minimum:"1"`
+}
+
+type RevisionOutput struct {
+	Body service.Revision
+}
+
+func registerHistoryRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-task-history",
+		Method:      http.MethodGet,
+		Path:        "/tasks/{id}/history",
+		Summary:     "List the revisions of a task, newest first",
+	}, func(ctx context.Context, input *ListHistoryInput) (*HistoryOutput, error) {
+		revisions, err := svc.History(ctx, input.ID, input.Before, input.Limit)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &HistoryOutput{Body: HistoryResponse{Items: revisions, Count: len(revisions)}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-task-revision",
+		Method:      http.MethodGet,
+		Path:        "/tasks/{id}/history/{rev}",
+		Summary:     "Get a task as it was at a revision",
+	}, func(ctx context.Context, input *RevisionInput) (*RevisionOutput, error) {
+		revision, err := svc.Revision(ctx, input.ID, input.Rev)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &RevisionOutput{Body: *revision}, nil
+	})
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/ical"
+	"task-api-huma-mongo/internal/service"
+)
+
+const icsProdID = "-//task-api-huma-mongo//Tasks//EN"
+
+// icalPriorities maps priorities onto the 1 (highest) to 9 scale of the
+// PRIORITY property.
+var icalPriorities = map[service.Priority]int{
+	service.PriorityUrgent: 1,
+	service.PriorityHigh:   3,
+	service.PriorityNormal: 5,
+	service.PriorityLow:    9,
+}
+
+type ExportICSInput struct {
+	TaskFilterParams
+}
+
+// ImportICSInput takes an iCalendar file; Resolve reads its VTODOs into
+// rows.
+type ImportICSInput struct {
+	DryRun  bool `query:"dryRun" doc:"Validate the VTODOs without inserting them"`
+	RawBody []byte
+
+	rows []importRow
+}
+
+func (i *ImportICSInput) Resolve(ctx huma.Context) []error {
+	mediaType, errs := bodyMediaType(ctx)
+	if errs != nil {
+		return errs
+	}
+	if ctx.Header("Content-Type") != "" && mediaType != ical.ContentType {
+		return []error{&bodyError{status: http.StatusUnsupportedMediaType, detail: &huma.ErrorDetail{
+			Message:  "Content-Type must be " + ical.ContentType,
+			Location: "header.Content-Type",
+			Value:    mediaType,
+		}}}
+	}
+
+	todos, todoErrs, err := ical.Decode(bytes.NewReader(i.RawBody))
+	if err != nil {
+		return []error{&bodyError{status: http.StatusBadRequest, detail: &huma.ErrorDetail{Message: err.Error(), Location: "body"}}}
+	}
+	i.rows = make([]importRow, len(todos))
+	for n, todo := range todos {
+		if err := todoErrs[n]; err != nil {
+			param := InvalidParam{Name: "row", Reason: err.Error()}
+			var propErr *ical.PropertyError
+			if errors.As(err, &propErr) {
+				param = InvalidParam{Name: propErr.Property, Reason: propErr.Err.Error()}
+			}
+			i.rows[n].invalid = []InvalidParam{param}
+			continue
+		}
+		i.rows[n].task = todoImportTask(todo)
+	}
+	return checkImportRows(i.rows)
+}
+
+func registerICSRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "export-tasks-ics",
+		Method:      http.MethodGet,
+		Path:        "/tasks.ics",
+		Summary:     "Export tasks as iCalendar",
+		Description: "Streams the tasks matching the same filters as list-tasks as VTODOs of one VCALENDAR. " +
+			"Each task keeps the same UID across exports.",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "iCalendar file",
+				Headers: map[string]*huma.Param{
+					"Content-Disposition": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+				Content: map[string]*huma.MediaType{
+					ical.ContentType: {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *ExportICSInput) (*huma.StreamResponse, error) {
+		cursor, err := svc.Export(ctx, input.Filter())
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return streamExport(ctx, cursor, "ics", exportFilename("tasks", "ics")), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "import-tasks-ics",
+		Method:      http.MethodPost,
+		Path:        "/tasks/import/ics",
+		Summary:     "Import tasks from iCalendar",
+		Description: "Creates one task per VTODO, validated like create-task and reported like import-tasks. " +
+			"VTODOs whose UID already belongs to a task are reported as duplicates, so importing a calendar twice is harmless.",
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				ical.ContentType: {Schema: &huma.Schema{Type: huma.TypeString}},
+			},
+		},
+		SkipValidateBody: true,
+		MaxBodyBytes:     importMaxBodyBytes,
+		BodyReadTimeout:  30 * time.Second,
+	}, func(ctx context.Context, input *ImportICSInput) (*ImportTasksOutput, error) {
+		return importRows(ctx, svc, input.rows, input.DryRun)
+	})
+
+	rawBodyContentTypes(api, "/tasks/import/ics")
+}
+
+// taskTodo maps a task onto a VTODO stamped at stamp.
+func taskTodo(task service.Task, stamp time.Time) ical.Todo {
+	todo := ical.Todo{
+		UID:         service.CalendarUID(task),
+		Stamp:       stamp,
+		Summary:     task.Title,
+		Description: task.Description,
+		Status:      ical.StatusNeedsAction,
+		Priority:    icalPriorities[task.Priority],
+		Categories:  task.Tags,
+		Created:     &task.CreatedAt,
+		Due:         task.DueAt,
+		Alarm:       task.RemindAt,
+	}
+	if task.Done {
+		todo.Status = ical.StatusCompleted
+		todo.Completed = task.CompletedAt
+	}
+	return todo
+}
+
+// todoImportTask maps a VTODO onto a task to import. A VTODO without
+// STATUS counts as done when it has a COMPLETED time.
+func todoImportTask(todo ical.Todo) service.ImportTask {
+	done := todo.Status == ical.StatusCompleted || (todo.Status == "" && todo.Completed != nil)
+	row := service.ImportTask{
+		CreateTaskRequest: service.CreateTaskRequest{
+			Title:       todo.Summary,
+			Description: todo.Description,
+			Done:        &done,
+			Priority:    todoPriority(todo.Priority),
+			Tags:        todo.Categories,
+			DueAt:       todo.Due,
+			RemindAt:    todo.Alarm,
+			UID:         todo.UID,
+		},
+		CreatedAt: todo.Created,
+	}
+	if done {
+		row.CompletedAt = todo.Completed
+	}
+	return row
+}
+
+// todoPriority maps PRIORITY back onto the four priorities; 0, undefined,
+// leaves the default.
+func todoPriority(priority int) service.Priority {
+	switch {
+	case priority == 0:
+		return ""
+	case priority == 1:
+		return service.PriorityUrgent
+	case priority <= 4:
+		return service.PriorityHigh
+	case priority == 5:
+		return service.PriorityNormal
+	default:
+		return service.PriorityLow
+	}
+}
+
+// icsTaskWriter writes tasks as the VTODOs of one VCALENDAR, all stamped
+// with the time of the export.
+type icsTaskWriter struct {
+	w     *ical.Writer
+	stamp time.Time
+}
+
+func newICSTaskWriter(w io.Writer) *icsTaskWriter {
+	return &icsTaskWriter{w: ical.NewWriter(w, icsProdID), stamp: time.Now().UTC()}
+}
+
+func (i *icsTaskWriter) Write(task service.Task) error { return i.w.WriteTodo(taskTodo(task, i.stamp)) }
+func (i *icsTaskWriter) Flush() error                  { return nil }
+func (i *icsTaskWriter) Close() error                  { return i.w.Close() }
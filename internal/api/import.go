@@ -0,0 +1,363 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	importMaxBodyBytes = 10 * 1024 * 1024
+	csvContentType     = "text/csv"
+	ndjsonContentType  = "application/x-ndjson"
+)
+
+// ImportTaskBody is one imported task. Rows of an export can be imported as
+// they are: id, version and the other read-only fields are ignored.
+type ImportTaskBody struct {
+	_ struct{} `additionalProperties:"true"`
+	CreateTaskBody
+	CreatedAt   *time.Time `json:"createdAt,omitempty" doc:"Defaults to the time of the import"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" doc:"Only for done tasks; defaults to createdAt"`
+}
+
+// ImportTasksInput takes the rows as CSV with a header line, NDJSON or a
+// JSON array. Resolve parses RawBody into rows; a row that cannot be parsed
+// is reported on its own instead of failing the request.
+type ImportTasksInput struct {
+	DryRun  bool `query:"dryRun" doc:"Validate the rows without inserting them"`
+	RawBody []byte
+
+	rows []importRow
+}
+
+type importRow struct {
+	task    service.ImportTask
+	invalid []InvalidParam
+}
+
+type ImportTasksOutput struct {
+	Body ImportResponse
+}
+
+type ImportResponse struct {
+	DryRun     bool              `json:"dryRun"`
+	Accepted   int               `json:"accepted"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Rows       []ImportRowResult `json:"rows"`
+}
+
+type ImportRowResult struct {
+	Index         int            `json:"index" doc:"Position of the row, from 0; the CSV header is not counted"`
+	Status        string         `json:"status" enum:"accepted,invalid,duplicate,failed" doc:"duplicate rows have the UID of an existing task; failed rows were valid but could not be inserted"`
+	Task          *service.Task  `json:"task,omitempty" doc:"Created task; on a dry run, the task that would be created, without id"`
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+	Message       string         `json:"message,omitempty"`
+}
+
+type importSchemas struct {
+	registry huma.Registry
+	row      *huma.Schema
+}
+
+// importRowSchema validates each row on its own, so that one bad row is
+// reported without failing the whole import.
+var importRowSchema = sync.OnceValue(func() *importSchemas {
+	registry := huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer)
+	return &importSchemas{
+		registry: registry,
+		row:      registry.Schema(reflect.TypeOf(ImportTaskBody{}), true, "ImportTaskBody"),
+	}
+})
+
+func (i *ImportTasksInput) Resolve(ctx huma.Context) []error {
+	mediaType, errs := bodyMediaType(ctx)
+	if errs != nil {
+		return errs
+	}
+
+	var err error
+	switch mediaType {
+	case "application/json":
+		i.rows, err = parseJSONImport(i.RawBody)
+	case ndjsonContentType:
+		i.rows, err = parseNDJSONImport(i.RawBody)
+	case csvContentType:
+		i.rows, err = parseCSVImport(i.RawBody)
+	default:
+		return []error{&bodyError{status: http.StatusUnsupportedMediaType, detail: &huma.ErrorDetail{
+			Message:  "Content-Type must be application/json, " + ndjsonContentType + " or " + csvContentType,
+			Location: "header.Content-Type",
+			Value:    mediaType,
+		}}}
+	}
+	if err != nil {
+		return []error{&bodyError{status: http.StatusBadRequest, detail: &huma.ErrorDetail{Message: err.Error(), Location: "body"}}}
+	}
+	return checkImportRows(i.rows)
+}
+
+func checkImportRows(rows []importRow) []error {
+	if len(rows) == 0 || len(rows) > service.MaxImportRows {
+		return []error{&bodyError{status: http.StatusBadRequest, detail: &huma.ErrorDetail{
+			Message:  fmt.Sprintf("an import must contain between 1 and %d rows", service.MaxImportRows),
+			Location: "body",
+			Value:    len(rows),
+		}}}
+	}
+	return nil
+}
+
+func parseJSONImport(body []byte) ([]importRow, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, errors.New("body must be a JSON array of tasks")
+	}
+	rows := make([]importRow, 0, len(items))
+	for n, item := range items {
+		rows = append(rows, decodeImportRow(n, item))
+	}
+	return rows, nil
+}
+
+func parseNDJSONImport(body []byte) ([]importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	var rows []importRow
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rows = append(rows, decodeImportRow(len(rows), line))
+		if len(rows) > service.MaxImportRows {
+			break
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// parseCSVImport reads CSV whose header names a subset of the export
+// columns, in any order. The id column is ignored.
+func parseCSVImport(body []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\ufeff"))))
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	header = slices.Clone(header)
+	for n, column := range header {
+		header[n] = strings.TrimSpace(column)
+		if !slices.Contains(taskCSVHeader, header[n]) {
+			return nil, fmt.Errorf("unknown column %q; columns must be among %s", column, strings.Join(taskCSVHeader, ","))
+		}
+	}
+	if !slices.Contains(header, "title") {
+		return nil, errors.New("the title column is required")
+	}
+
+	var rows []importRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			rows = append(rows, importRow{invalid: []InvalidParam{{Name: "row", Reason: fmt.Sprintf("line %d has %d fields, the header has %d", parseErr.Line, len(record), len(header))}}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields := map[string]any{}
+		for n, value := range record {
+			if value == "" {
+				continue
+			}
+			switch column := header[n]; column {
+			case "id":
+			case "done":
+				if done, ok := map[string]bool{"true": true, "false": false}[strings.ToLower(value)]; ok {
+					fields[column] = done
+				} else {
+					fields[column] = value
+				}
+			case "tags":
+				tags := []any{}
+				for _, tag := range strings.Split(value, csvTagSeparator) {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						tags = append(tags, tag)
+					}
+				}
+				fields[column] = tags
+			default:
+				fields[column] = value
+			}
+		}
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, decodeImportRow(len(rows), raw))
+		if len(rows) > service.MaxImportRows {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// decodeImportRow validates raw against the ImportTaskBody schema and
+// decodes it. Errors are reported relative to the row.
+func decodeImportRow(index int, raw []byte) importRow {
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return importRow{invalid: []InvalidParam{{Name: "row", Reason: err.Error()}}}
+	}
+
+	schemas := importRowSchema()
+	pb := huma.NewPathBuffer([]byte{}, 0)
+	pb.Push("body")
+	pb.PushIndex(index)
+	prefix := pb.String()
+	res := &huma.ValidateResult{}
+	huma.Validate(schemas.registry, schemas.row, pb, huma.ModeWriteToServer, data, res)
+	if len(res.Errors) > 0 {
+		var row importRow
+		for _, err := range res.Errors {
+			param := InvalidParam{Name: "row", Reason: err.Error()}
+			var detail *huma.ErrorDetail
+			if errors.As(err, &detail) {
+				param.Reason = detail.Message
+				if name := strings.TrimPrefix(strings.TrimPrefix(detail.Location, prefix), "."); name != "" {
+					param.Name = name
+				}
+			}
+			row.invalid = append(row.invalid, param)
+		}
+		return row
+	}
+
+	var body ImportTaskBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return importRow{invalid: []InvalidParam{{Name: "row", Reason: err.Error()}}}
+	}
+	return importRow{task: service.ImportTask{
+		CreateTaskRequest: createTaskRequest(body.CreateTaskBody),
+		CreatedAt:         body.CreatedAt,
+		CompletedAt:       body.CompletedAt,
+	}}
+}
+
+func registerImportRoutes(api huma.API, svc *service.Service) {
+	rowSchema := api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf(ImportTaskBody{}), true, "ImportTaskBody")
+	huma.Register(api, huma.Operation{
+		OperationID: "import-tasks",
+		Method:      http.MethodPost,
+		Path:        "/tasks/import",
+		Summary:     "Import tasks",
+		Description: "Creates one task per row of a CSV file with a header line (the columns of export-tasks, tags joined with \";\"), " +
+			"NDJSON or a JSON array. Every row is validated like create-task and reported on its own; " +
+			"valid rows are inserted in batches even when others fail.",
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				"application/json": {Schema: &huma.Schema{Type: huma.TypeArray, Items: rowSchema}},
+				ndjsonContentType:  {Schema: rowSchema},
+				csvContentType:     {Schema: &huma.Schema{Type: huma.TypeString}},
+			},
+		},
+		// Resolve validates every row against the row schema.
+		SkipValidateBody: true,
+		MaxBodyBytes:     importMaxBodyBytes,
+		BodyReadTimeout:  30 * time.Second,
+	}, func(ctx context.Context, input *ImportTasksInput) (*ImportTasksOutput, error) {
+		return importRows(ctx, svc, input.rows, input.DryRun)
+	})
+
+	rawBodyContentTypes(api, "/tasks/import")
+}
+
+// rawBodyContentTypes leaves the POST operation at path documenting only
+// the content types it declares: RawBody documents itself as
+// application/octet-stream, which the import operations do not accept.
+func rawBodyContentTypes(api huma.API, path string) {
+	delete(api.OpenAPI().Paths[path].Post.RequestBody.Content, "application/octet-stream")
+}
+
+// importRows imports the rows that were parsed and reports every row,
+// including those that could not be parsed.
+func importRows(ctx context.Context, svc *service.Service, rows []importRow, dryRun bool) (*ImportTasksOutput, error) {
+	resp := ImportResponse{DryRun: dryRun, Rows: make([]ImportRowResult, len(rows))}
+	var (
+		tasks   []service.ImportTask
+		taskRow []int
+	)
+	for n, row := range rows {
+		resp.Rows[n] = ImportRowResult{Index: n, Status: "invalid", InvalidParams: row.invalid}
+		if row.invalid == nil {
+			tasks = append(tasks, row.task)
+			taskRow = append(taskRow, n)
+		}
+	}
+
+	if len(tasks) > 0 {
+		results, err := svc.Import(ctx, tasks, dryRun)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		for j, result := range results {
+			row := &resp.Rows[taskRow[j]]
+			var (
+				vErr *service.ValidationError
+				cErr *service.ConflictError
+			)
+			switch {
+			case result.Err == nil:
+				row.Status = "accepted"
+				row.Task = result.Task
+			case errors.As(result.Err, &vErr):
+				row.InvalidParams = []InvalidParam{{Name: vErr.Field, Reason: vErr.Message}}
+			case errors.As(result.Err, &cErr):
+				row.Status = "duplicate"
+				row.Message = cErr.Message
+			default:
+				row.Status = "failed"
+				row.Message = result.Err.Error()
+			}
+		}
+	}
+
+	for _, row := range resp.Rows {
+		switch row.Status {
+		case "accepted":
+			resp.Accepted++
+		case "duplicate":
+			resp.Duplicates++
+		default:
+			resp.Failed++
+		}
+	}
+	return &ImportTasksOutput{Body: resp}, nil
+}
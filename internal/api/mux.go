@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CustomMethodMux lets operations use custom-method paths such as
+// /tags/{tag}:rename, which http.ServeMux cannot match because a wildcard
+// must span a whole segment. Such routes are registered on the bare
+// wildcard and dispatched on the ":method" suffix of its value.
+type CustomMethodMux struct {
+	*http.ServeMux
+	methods map[string]map[string]http.HandlerFunc
+}
+
+func NewCustomMethodMux(mux *http.ServeMux) *CustomMethodMux {
+	return &CustomMethodMux{ServeMux: mux, methods: map[string]map[string]http.HandlerFunc{}}
+}
+
+func (m *CustomMethodMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	cut := strings.LastIndex(pattern, "}:")
+	if cut < 0 || strings.Contains(pattern[cut+2:], "/") {
+		m.ServeMux.HandleFunc(pattern, handler)
+		return
+	}
+	base, method := pattern[:cut+1], pattern[cut+1:]
+	wildcard := base[strings.LastIndex(base, "{")+1 : len(base)-1]
+
+	methods, ok := m.methods[base]
+	if !ok {
+		methods = map[string]http.HandlerFunc{}
+		m.methods[base] = methods
+		m.ServeMux.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+			value := r.PathValue(wildcard)
+			cut := strings.LastIndex(value, ":")
+			if cut < 0 || methods[value[cut:]] == nil {
+				http.NotFound(w, r)
+				return
+			}
+			r.SetPathValue(wildcard, value[:cut])
+			methods[value[cut:]](w, r)
+		})
+	}
+	methods[method] = handler
+}
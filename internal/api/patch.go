@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// PatchOperationBody documents one RFC 6902 operation of a JSON Patch.
+type PatchOperationBody struct {
+	Op    string `json:"op" enum:"add,remove,replace,test"`
+	Path  string `json:"path" doc:"/title, /description, /done, /priority, /projectId, /tags, /tags/{index}, /tags/- (append), /dueAt or /remindAt; test also accepts /version" example:"/tags/-"`
+	Value any    `json:"value,omitempty"`
+}
+
+type updateSchemas struct {
+	registry huma.Registry
+	update   *huma.Schema
+	patch    *huma.Schema
+}
+
+func newUpdateSchemas(registry huma.Registry) *updateSchemas {
+	minOps, maxOps := 1, service.MaxPatchOperations
+	patch := &huma.Schema{
+		Type:     huma.TypeArray,
+		Items:    registry.Schema(reflect.TypeOf(PatchOperationBody{}), true, "PatchOperationBody"),
+		MinItems: &minOps,
+		MaxItems: &maxOps,
+	}
+	patch.PrecomputeMessages()
+	return &updateSchemas{
+		registry: registry,
+		update:   registry.Schema(reflect.TypeOf(UpdateTaskBody{}), true, "UpdateTaskBody"),
+		patch:    patch,
+	}
+}
+
+// bodySchemas validates update bodies, which update-task reads raw because
+// their shape depends on the Content-Type.
+var bodySchemas = sync.OnceValue(func() *updateSchemas {
+	return newUpdateSchemas(huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer))
+})
+
+// bodyError is a request body error whose status replaces the default 422.
+type bodyError struct {
+	status int
+	detail *huma.ErrorDetail
+}
+
+func (e *bodyError) Error() string                  { return e.detail.Error() }
+func (e *bodyError) GetStatus() int                 { return e.status }
+func (e *bodyError) ErrorDetail() *huma.ErrorDetail { return e.detail }
+
+func (i *UpdateTaskInput) Resolve(ctx huma.Context) []error {
+	mediaType, errs := bodyMediaType(ctx)
+	if errs != nil {
+		return errs
+	}
+
+	var data any
+	if err := json.Unmarshal(i.RawBody, &data); err != nil {
+		return []error{&bodyError{status: http.StatusBadRequest, detail: &huma.ErrorDetail{Message: err.Error(), Location: "body"}}}
+	}
+	schemas := bodySchemas()
+
+	switch mediaType {
+	case "application/json":
+		if errs := validateBody(schemas, schemas.update, data); len(errs) > 0 {
+			return errs
+		}
+		i.update = &UpdateTaskBody{}
+		if err := json.Unmarshal(i.RawBody, i.update); err != nil {
+			return []error{&huma.ErrorDetail{Message: err.Error(), Location: "body"}}
+		}
+		return i.update.resolve()
+
+	case mergePatchContentType:
+		fields, ok := data.(map[string]any)
+		if !ok {
+			return []error{&huma.ErrorDetail{Message: "merge patch must be a JSON object", Location: "body"}}
+		}
+		// Nulls remove fields; everything else must be a valid value.
+		values := make(map[string]any, len(fields))
+		for name, value := range fields {
+			if value != nil {
+				values[name] = value
+			}
+		}
+		if errs := validateBody(schemas, schemas.update, values); len(errs) > 0 {
+			return errs
+		}
+		if len(fields) == 0 {
+			return []error{&huma.ErrorDetail{Message: "at least one field must be provided", Location: "body"}}
+		}
+		i.merge = fields
+		return nil
+
+	case jsonPatchContentType:
+		if errs := validateBody(schemas, schemas.patch, data); len(errs) > 0 {
+			return errs
+		}
+		if err := json.Unmarshal(i.RawBody, &i.patch); err != nil {
+			return []error{&huma.ErrorDetail{Message: err.Error(), Location: "body"}}
+		}
+		return nil
+	}
+
+	return []error{&bodyError{status: http.StatusUnsupportedMediaType, detail: &huma.ErrorDetail{
+		Message:  "Content-Type must be application/json, " + mergePatchContentType + " or " + jsonPatchContentType,
+		Location: "header.Content-Type",
+		Value:    mediaType,
+	}}}
+}
+
+// bodyMediaType returns the media type of the request body, which
+// defaults to application/json.
+func bodyMediaType(ctx huma.Context) (string, []error) {
+	header := ctx.Header("Content-Type")
+	if header == "" {
+		return "application/json", nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", []error{&bodyError{status: http.StatusUnsupportedMediaType, detail: &huma.ErrorDetail{Message: "invalid Content-Type", Location: "header.Content-Type", Value: header}}}
+	}
+	return mediaType, nil
+}
+
+func validateBody(schemas *updateSchemas, schema *huma.Schema, data any) []error {
+	pb := huma.NewPathBuffer([]byte{}, 0)
+	pb.Push("body")
+	res := &huma.ValidateResult{}
+	huma.Validate(schemas.registry, schema, pb, huma.ModeWriteToServer, data, res)
+	return res.Errors
+}
+
+func registerUpdateTaskRoute(api huma.API, svc *service.Service) {
+	schemas := newUpdateSchemas(api.OpenAPI().Components.Schemas)
+	huma.Register(api, huma.Operation{
+		OperationID: "update-task",
+		Method:      http.MethodPatch,
+		Path:        "/tasks/{id}",
+		Summary:     "Update task",
+		Description: "Send the fields to change as application/json, an RFC 7396 merge patch (null removes a field) " +
+			"or an RFC 6902 JSON Patch. Patches are applied to the stored task in a single atomic update.",
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				"application/json":    {Schema: schemas.update},
+				mergePatchContentType: {Schema: schemas.update},
+				jsonPatchContentType:  {Schema: schemas.patch},
+			},
+		},
+		// Resolve validates the body against the schema of its Content-Type.
+		SkipValidateBody: true,
+		MaxBodyBytes:     1024 * 1024,
+		BodyReadTimeout:  5 * time.Second,
+	}, func(ctx context.Context, input *UpdateTaskInput) (*TaskOutput, error) {
+		ifVersion := ifMatchVersion(input.IfMatch)
+		var (
+			task *service.Task
+			err  error
+		)
+		switch {
+		case input.patch != nil:
+			task, err = svc.Patch(ctx, input.ID, input.patch, ifVersion)
+		case input.merge != nil:
+			task, err = svc.MergePatch(ctx, input.ID, input.merge, ifVersion)
+		default:
+			req := updateTaskRequest(*input.update)
+			req.IfVersion = ifVersion
+			task, err = svc.Update(ctx, input.ID, req)
+		}
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+
+		return newTaskOutput(task), nil
+	})
+
+	// RawBody documents itself as application/octet-stream, which
+	// update-task does not accept.
+	delete(api.OpenAPI().Paths["/tasks/{id}"].Patch.RequestBody.Content, "application/octet-stream")
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type ProjectOutput struct {
+	Body service.Project
+}
+
+type ListProjectsOutput struct {
+	Body ListProjectsResponse
+}
+
+type ListProjectsResponse struct {
+	Items []service.Project `json:"items"`
+	Count int               `json:"count"`
+}
+
+type CreateProjectInput struct {
+	Body CreateProjectBody
+}
+
+type CreateProjectBody struct {
+	Name        string `json:"name" minLength:"1" maxLength:"100"`
+	Description string `json:"description,omitempty"`
+}
+
+type UpdateProjectInput struct {
+	ID   string `path:"id"`
+	Body UpdateProjectBody
+}
+
+type UpdateProjectBody struct {
+	Name        *string `json:"name,omitempty" minLength:"1" maxLength:"100"`
+	Description *string `json:"description,omitempty"`
+}
+
+type ProjectIDInput struct {
+	ID string `path:"id"`
+}
+
+type DeleteProjectInput struct {
+	ID   string `path:"id"`
+	Mode string `query:"mode" enum:"restrict,cascade" default:"restrict" doc:"restrict fails while the project has tasks, cascade moves them to the trash"`
+}
+
+type DeleteProjectOutput struct {
+	DeletedTasks int64 `header:"X-Deleted-Tasks"`
+}
+
+type ListProjectTasksInput struct {
+	ID string `path:"id"`
+	ListTasksInput
+}
+
+func (i *CreateProjectInput) Resolve(ctx huma.Context) []error {
+	name := strings.TrimSpace(i.Body.Name)
+	if name == "" {
+		return []error{&huma.ErrorDetail{Message: "name must not be blank", Location: "body.name", Value: i.Body.Name}}
+	}
+	i.Body.Name = name
+	return nil
+}
+
+func (i *UpdateProjectInput) Resolve(ctx huma.Context) []error {
+	if i.Body.Name == nil && i.Body.Description == nil {
+		return []error{&huma.ErrorDetail{Message: "at least one field must be provided", Location: "body"}}
+	}
+	return nil
+}
+
+func registerProjectRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "create-project",
+		Method:        http.MethodPost,
+		Path:          "/projects",
+		Summary:       "Create a project",
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *CreateProjectInput) (*ProjectOutput, error) {
+		project, err := svc.CreateProject(ctx, service.CreateProjectRequest{
+			Name:        input.Body.Name,
+			Description: input.Body.Description,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ProjectOutput{Body: *project}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-projects",
+		Method:      http.MethodGet,
+		Path:        "/projects",
+		Summary:     "List projects",
+	}, func(ctx context.Context, input *struct{}) (*ListProjectsOutput, error) {
+		projects, err := svc.ListProjects(ctx)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ListProjectsOutput{Body: ListProjectsResponse{Items: projects, Count: len(projects)}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project",
+		Method:      http.MethodGet,
+		Path:        "/projects/{id}",
+		Summary:     "Get project by ID",
+	}, func(ctx context.Context, input *ProjectIDInput) (*ProjectOutput, error) {
+		project, err := svc.GetProject(ctx, input.ID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ProjectOutput{Body: *project}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project",
+		Method:      http.MethodPatch,
+		Path:        "/projects/{id}",
+		Summary:     "Update project",
+	}, func(ctx context.Context, input *UpdateProjectInput) (*ProjectOutput, error) {
+		project, err := svc.UpdateProject(ctx, input.ID, service.UpdateProjectRequest{
+			Name:        input.Body.Name,
+			Description: input.Body.Description,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ProjectOutput{Body: *project}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-project",
+		Method:        http.MethodDelete,
+		Path:          "/projects/{id}",
+		Summary:       "Delete project",
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *DeleteProjectInput) (*DeleteProjectOutput, error) {
+		deleted, err := svc.DeleteProject(ctx, input.ID, service.DeleteMode(input.Mode))
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &DeleteProjectOutput{DeletedTasks: deleted}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-tasks",
+		Method:      http.MethodGet,
+		Path:        "/projects/{id}/tasks",
+		Summary:     "List tasks of a project",
+	}, func(ctx context.Context, input *ListProjectTasksInput) (*ListTasksOutput, error) {
+		page, err := svc.ListProjectTasks(ctx, input.ID, input.Filter(), input.Options())
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newListTasksOutput(page), nil
+	})
+}
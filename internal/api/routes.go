@@ -7,6 +7,7 @@ import (
 
 	"github.com/danielgtaylor/huma/v2"
 
+	"task-api-huma-mongo/internal/markdown"
 	"task-api-huma-mongo/internal/service"
 )
 
@@ -40,17 +41,27 @@ func RegisterRoutes(api huma.API, svc *service.Service) {
 		Path:          "/tasks",
 		Summary:       "Create a task",
 		DefaultStatus: http.StatusCreated,
-	}, func(ctx context.Context, input *CreateTaskInput) (*TaskOutput, error) {
-		task, err := svc.Create(ctx, service.CreateTaskRequest{
-			Title: input.Body.Title,
-			Done:  input.Body.Done,
-			Tags:  input.Body.Tags,
-		})
+	}, func(ctx context.Context, input *CreateTaskInput) (*CreateTaskOutput, error) {
+		req := createTaskRequest(input.Body)
+		var (
+			task     *service.Task
+			replayed bool
+			err      error
+		)
+		if input.IdempotencyKey != "" {
+			task, replayed, err = svc.CreateIdempotent(ctx, input.IdempotencyKey, input.Body.hash(), req)
+		} else {
+			task, err = svc.Create(ctx, req)
+		}
 		if err != nil {
 			return nil, MapServiceError(ctx, err)
 		}
 
-		return &TaskOutput{Body: *task}, nil
+		out := &CreateTaskOutput{ETag: versionETag(task.Version), Body: *task}
+		if replayed {
+			out.IdempotentReplayed = "true"
+		}
+		return out, nil
 	})
 
 	huma.Register(api, huma.Operation{
@@ -59,23 +70,12 @@ func RegisterRoutes(api huma.API, svc *service.Service) {
 		Path:        "/tasks",
 		Summary:     "List tasks",
 	}, func(ctx context.Context, input *ListTasksInput) (*ListTasksOutput, error) {
-		var done *bool
-		if input.Done.IsSet {
-			value := input.Done.Value
-			done = &value
-		}
-		tasks, err := svc.List(ctx, service.TaskFilter{
-			Done: done,
-			Tag:  input.Tag,
-		})
+		page, err := svc.List(ctx, input.Filter(), input.Options())
 		if err != nil {
 			return nil, MapServiceError(ctx, err)
 		}
 
-		return &ListTasksOutput{Body: ListTasksResponse{
-			Items: tasks,
-			Count: len(tasks),
-		}}, nil
+		return newListTasksOutput(page), nil
 	})
 
 	huma.Register(api, huma.Operation{
@@ -83,43 +83,91 @@ func RegisterRoutes(api huma.API, svc *service.Service) {
 		Method:      http.MethodGet,
 		Path:        "/tasks/{id}",
 		Summary:     "Get task by ID",
-	}, func(ctx context.Context, input *TaskIDInput) (*TaskOutput, error) {
+	}, func(ctx context.Context, input *GetTaskInput) (*TaskOutput, error) {
 		task, err := svc.Get(ctx, input.ID)
 		if err != nil {
 			return nil, MapServiceError(ctx, err)
 		}
-
-		return &TaskOutput{Body: *task}, nil
-	})
-
-	huma.Register(api, huma.Operation{
-		OperationID: "update-task",
-		Method:      http.MethodPatch,
-		Path:        "/tasks/{id}",
-		Summary:     "Update task",
-	}, func(ctx context.Context, input *UpdateTaskInput) (*TaskOutput, error) {
-		task, err := svc.Update(ctx, input.ID, service.UpdateTaskRequest{
-			Title: input.Body.Title,
-			Done:  input.Body.Done,
-			Tags:  input.Body.Tags,
-		})
-		if err != nil {
-			return nil, MapServiceError(ctx, err)
+		if input.Render == "html" && task.Description != "" {
+			html, err := markdown.RenderHTML(task.Description)
+			if err != nil {
+				return nil, MapServiceError(ctx, err)
+			}
+			task.DescriptionHTML = html
 		}
 
-		return &TaskOutput{Body: *task}, nil
+		return newTaskOutput(task), nil
 	})
 
+	registerUpdateTaskRoute(api, svc)
+
 	huma.Register(api, huma.Operation{
 		OperationID:   "delete-task",
 		Method:        http.MethodDelete,
 		Path:          "/tasks/{id}",
-		Summary:       "Delete task",
+		Summary:       "Move task to the trash",
 		DefaultStatus: http.StatusNoContent,
-	}, func(ctx context.Context, input *TaskIDInput) (*struct{}, error) {
-		if err := svc.Delete(ctx, input.ID); err != nil {
+	}, func(ctx context.Context, input *DeleteTaskInput) (*struct{}, error) {
+		if err := svc.Delete(ctx, input.ID, ifMatchVersion(input.IfMatch)); err != nil {
 			return nil, MapServiceError(ctx, err)
 		}
 		return nil, nil
 	})
+
+	registerChecklistRoutes(api, svc)
+	registerDependencyRoutes(api, svc)
+	registerProjectRoutes(api, svc)
+	registerTrashRoutes(api, svc)
+	registerHistoryRoutes(api, svc)
+	registerBatchRoutes(api, svc)
+	registerBulkRoutes(api, svc)
+	registerTagRoutes(api, svc)
+	registerStatsRoutes(api, svc)
+	registerExportRoutes(api, svc)
+	registerImportRoutes(api, svc)
+	registerICSRoutes(api, svc)
+	registerTodoTxtRoutes(api, svc)
+	registerWebhookRoutes(api, svc)
+}
+
+func createTaskRequest(body CreateTaskBody) service.CreateTaskRequest {
+	return service.CreateTaskRequest{
+		Title:       body.Title,
+		Description: body.Description,
+		Done:        body.Done,
+		Priority:    service.Priority(body.Priority),
+		ProjectID:   body.ProjectID,
+		Tags:        body.Tags,
+		DueAt:       body.DueAt,
+		RemindAt:    body.RemindAt,
+	}
+}
+
+func updateTaskRequest(body UpdateTaskBody) service.UpdateTaskRequest {
+	return service.UpdateTaskRequest{
+		Title:       body.Title,
+		Description: body.Description,
+		Done:        body.Done,
+		Priority:    priorityPtr(body.Priority),
+		ProjectID:   body.ProjectID,
+		Tags:        body.Tags,
+		DueAt:       body.DueAt,
+		RemindAt:    body.RemindAt,
+	}
+}
+
+func priorityPtr(value *string) *service.Priority {
+	if value == nil {
+		return nil
+	}
+	priority := service.Priority(*value)
+	return &priority
+}
+
+func newListTasksOutput(page *service.TaskPage) *ListTasksOutput {
+	return &ListTasksOutput{Body: ListTasksResponse{
+		Items:      page.Items,
+		Count:      len(page.Items),
+		NextCursor: page.NextCursor,
+	}}
 }
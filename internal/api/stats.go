@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type TaskStatsInput struct {
+	TaskFilterParams
+}
+
+type TaskStatsOutput struct {
+	Body service.TaskStats
+}
+
+type TimeseriesInput struct {
+	Bucket string                   `query:"bucket" enum:"hour,day,week,month" default:"day"`
+	From   OptionalParam[time.Time] `query:"from" doc:"Start of the range; defaults to 30 buckets before to"`
+	To     OptionalParam[time.Time] `query:"to" doc:"End of the range, exclusive; defaults to now"`
+	TZ     string                   `query:"tz" default:"UTC" doc:"IANA time zone the buckets are aligned to" example:"Europe/Rome"`
+
+	location *time.Location
+}
+
+type TimeseriesOutput struct {
+	Body service.Timeseries
+}
+
+func (i *TimeseriesInput) Resolve(ctx huma.Context) []error {
+	location, err := time.LoadLocation(i.TZ)
+	if err != nil || i.TZ == "Local" {
+		return []error{&huma.ErrorDetail{Message: "unknown time zone", Location: "query.tz", Value: i.TZ}}
+	}
+	i.location = location
+	return nil
+}
+
+func registerStatsRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "task-stats",
+		Method:      http.MethodGet,
+		Path:        "/tasks/stats",
+		Summary:     "Task statistics",
+		Description: "Totals, per-tag counts and the age distribution of open tasks, computed over the tasks " +
+			"matching the same filters as list-tasks.",
+	}, func(ctx context.Context, input *TaskStatsInput) (*TaskStatsOutput, error) {
+		stats, err := svc.Stats(ctx, input.Filter())
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &TaskStatsOutput{Body: *stats}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "task-timeseries",
+		Method:      http.MethodGet,
+		Path:        "/tasks/stats/timeseries",
+		Summary:     "Tasks created and completed over time",
+		Description: "Counts the tasks created and the tasks completed in each bucket of the range, " +
+			"with buckets aligned to tz. Weeks start on Monday.",
+	}, func(ctx context.Context, input *TimeseriesInput) (*TimeseriesOutput, error) {
+		series, err := svc.Timeseries(ctx, service.TimeseriesQuery{
+			Bucket:   service.TimeBucket(input.Bucket),
+			From:     input.From.Ptr(),
+			To:       input.To.Ptr(),
+			Location: input.location,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &TimeseriesOutput{Body: *series}, nil
+	})
+}
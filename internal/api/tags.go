@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type ListTagsOutput struct {
+	Body ListTagsResponse
+}
+
+type ListTagsResponse struct {
+	Items []service.TagCount `json:"items"`
+	Count int                `json:"count"`
+}
+
+type RenameTagInput struct {
+	Tag  string `path:"tag"`
+	Body RenameTagBody
+}
+
+type RenameTagBody struct {
+	Name string `json:"name" minLength:"1" maxLength:"50" doc:"New name; renaming to a tag in use merges the two"`
+}
+
+type MergeTagsInput struct {
+	Body MergeTagsBody
+}
+
+type MergeTagsBody struct {
+	Tags []string `json:"tags" minItems:"1" maxItems:"50" doc:"Tags to replace"`
+	Into string   `json:"into" minLength:"1" maxLength:"50" doc:"Tag that replaces them"`
+}
+
+type TagChangeOutput struct {
+	Body service.TagChange
+}
+
+func registerTagRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-tags",
+		Method:      http.MethodGet,
+		Path:        "/tags",
+		Summary:     "List tags with usage counts",
+	}, func(ctx context.Context, input *struct{}) (*ListTagsOutput, error) {
+		tags, err := svc.ListTags(ctx)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ListTagsOutput{Body: ListTagsResponse{Items: tags, Count: len(tags)}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "rename-tag",
+		Method:      http.MethodPost,
+		Path:        "/tags/{tag}:rename",
+		Summary:     "Rename a tag on every task",
+	}, func(ctx context.Context, input *RenameTagInput) (*TagChangeOutput, error) {
+		change, err := svc.RenameTag(ctx, input.Tag, input.Body.Name)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &TagChangeOutput{Body: *change}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "merge-tags",
+		Method:      http.MethodPost,
+		Path:        "/tags:merge",
+		Summary:     "Merge tags into one",
+	}, func(ctx context.Context, input *MergeTagsInput) (*TagChangeOutput, error) {
+		change, err := svc.MergeTags(ctx, input.Body.Tags, input.Body.Into)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &TagChangeOutput{Body: *change}, nil
+	})
+}
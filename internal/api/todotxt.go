@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+	"task-api-huma-mongo/internal/todotxt"
+)
+
+type ExportTodoTxtInput struct {
+	TaskFilterParams
+}
+
+// ImportTodoTxtInput takes a todo.txt file; Resolve reads its lines into
+// rows.
+type ImportTodoTxtInput struct {
+	DryRun  bool `query:"dryRun" doc:"Validate the lines without inserting them"`
+	RawBody []byte
+
+	rows []importRow
+}
+
+func (i *ImportTodoTxtInput) Resolve(ctx huma.Context) []error {
+	mediaType, errs := bodyMediaType(ctx)
+	if errs != nil {
+		return errs
+	}
+	if ctx.Header("Content-Type") != "" && mediaType != todotxt.ContentType {
+		return []error{&bodyError{status: http.StatusUnsupportedMediaType, detail: &huma.ErrorDetail{
+			Message:  "Content-Type must be " + todotxt.ContentType,
+			Location: "header.Content-Type",
+			Value:    mediaType,
+		}}}
+	}
+
+	tasks, err := todotxt.Decode(bytes.NewReader(i.RawBody))
+	if err != nil {
+		return []error{&bodyError{status: http.StatusBadRequest, detail: &huma.ErrorDetail{Message: err.Error(), Location: "body"}}}
+	}
+	i.rows = make([]importRow, len(tasks))
+	for n, task := range tasks {
+		i.rows[n] = todoTxtImportRow(task)
+	}
+	return checkImportRows(i.rows)
+}
+
+func registerTodoTxtRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "export-tasks-todotxt",
+		Method:      http.MethodGet,
+		Path:        "/tasks.txt",
+		Summary:     "Export tasks as todo.txt",
+		Description: "Streams the tasks matching the same filters as list-tasks in the todo.txt format, one task per line. " +
+			"Tags become +projects, or @contexts when they start with @, and dueAt a due: tag. " +
+			"todo.txt cannot escape words, so title words like +1 or @home are imported back as tags.",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "todo.txt file",
+				Headers: map[string]*huma.Param{
+					"Content-Disposition": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+				Content: map[string]*huma.MediaType{
+					todotxt.ContentType: {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *ExportTodoTxtInput) (*huma.StreamResponse, error) {
+		cursor, err := svc.Export(ctx, input.Filter())
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return streamExport(ctx, cursor, "todotxt", exportFilename("todo", "txt")), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "import-tasks-todotxt",
+		Method:      http.MethodPost,
+		Path:        "/tasks/import/todotxt",
+		Summary:     "Import tasks from todo.txt",
+		Description: "Creates one task per non-blank line, validated like create-task and reported like import-tasks. " +
+			"x marks done tasks, (A) to (D) and beyond set the priority, the creation date sets createdAt, " +
+			"+projects and @contexts become tags and due: sets dueAt.",
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				todotxt.ContentType: {Schema: &huma.Schema{Type: huma.TypeString}},
+			},
+		},
+		SkipValidateBody: true,
+		MaxBodyBytes:     importMaxBodyBytes,
+		BodyReadTimeout:  30 * time.Second,
+	}, func(ctx context.Context, input *ImportTodoTxtInput) (*ImportTasksOutput, error) {
+		return importRows(ctx, svc, input.rows, input.DryRun)
+	})
+
+	rawBodyContentTypes(api, "/tasks/import/todotxt")
+}
+
+// todoTxtImportRow maps a todo.txt line onto a row to import.
+func todoTxtImportRow(line todotxt.Task) importRow {
+	task, err := line.ImportTask()
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		return importRow{invalid: []InvalidParam{{Name: verr.Field, Reason: verr.Message}}}
+	}
+	return importRow{task: task}
+}
+
+// todoTxtTaskWriter writes tasks as todo.txt lines.
+type todoTxtTaskWriter struct {
+	w *todotxt.Writer
+}
+
+func newTodoTxtTaskWriter(w io.Writer) *todoTxtTaskWriter {
+	return &todoTxtTaskWriter{w: todotxt.NewWriter(w)}
+}
+
+func (t *todoTxtTaskWriter) Write(task service.Task) error { return t.w.Write(todotxt.FromTask(task)) }
+func (t *todoTxtTaskWriter) Flush() error                  { return nil }
+func (t *todoTxtTaskWriter) Close() error                  { return nil }
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type ListTrashInput struct {
+	Sort   string `query:"sort" doc:"Comma-separated sort fields (createdAt, title, done, priority), prefix with - for descending"`
+	Cursor string `query:"cursor" doc:"Opaque cursor returned as nextCursor by the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"200" default:"50"`
+}
+
+type EmptyTrashOutput struct {
+	Body EmptyTrashResponse
+}
+
+type EmptyTrashResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+func (i *ListTrashInput) Resolve(ctx huma.Context) []error {
+	i.Sort = strings.TrimSpace(i.Sort)
+	i.Cursor = strings.TrimSpace(i.Cursor)
+	return nil
+}
+
+func registerTrashRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-trash",
+		Method:      http.MethodGet,
+		Path:        "/trash",
+		Summary:     "List deleted tasks",
+	}, func(ctx context.Context, input *ListTrashInput) (*ListTasksOutput, error) {
+		page, err := svc.Trash(ctx, service.ListOptions{
+			Sort:   input.Sort,
+			Cursor: input.Cursor,
+			Limit:  input.Limit,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newListTasksOutput(page), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/restore",
+		Summary:     "Restore a deleted task",
+	}, func(ctx context.Context, input *TaskIDInput) (*TaskOutput, error) {
+		task, err := svc.Restore(ctx, input.ID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return newTaskOutput(task), nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "purge-task",
+		Method:        http.MethodDelete,
+		Path:          "/trash/{id}",
+		Summary:       "Permanently delete a task from the trash",
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *TaskIDInput) (*struct{}, error) {
+		if err := svc.Purge(ctx, input.ID); err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "empty-trash",
+		Method:      http.MethodDelete,
+		Path:        "/trash",
+		Summary:     "Permanently delete every task in the trash",
+	}, func(ctx context.Context, input *struct{}) (*EmptyTrashOutput, error) {
+		purged, err := svc.EmptyTrash(ctx)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &EmptyTrashOutput{Body: EmptyTrashResponse{Purged: purged}}, nil
+	})
+}
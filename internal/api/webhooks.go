@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// WebhookResponse is a webhook; Secret is only set in the response to
+// create-webhook.
+type WebhookResponse struct {
+	service.Webhook
+	Secret string `json:"secret,omitempty" doc:"Key of the X-Webhook-Signature HMAC, only returned on creation"`
+}
+
+type WebhookOutput struct {
+	Body WebhookResponse
+}
+
+type ListWebhooksOutput struct {
+	Body ListWebhooksResponse
+}
+
+type ListWebhooksResponse struct {
+	Items []service.Webhook `json:"items"`
+	Count int               `json:"count"`
+}
+
+type CreateWebhookInput struct {
+	Body CreateWebhookBody
+}
+
+type CreateWebhookBody struct {
+	URL    string   `json:"url" minLength:"1" maxLength:"2048" format:"uri"`
+	Events []string `json:"events" minItems:"1" uniqueItems:"true" enum:"task.created,task.updated,task.deleted,task.purged"`
+	Secret string   `json:"secret,omitempty" minLength:"16" maxLength:"256" doc:"Generated when omitted"`
+	Active *bool    `json:"active,omitempty" doc:"Defaults to true; inactive webhooks receive no events"`
+}
+
+type UpdateWebhookInput struct {
+	ID   string `path:"id"`
+	Body UpdateWebhookBody
+}
+
+type UpdateWebhookBody struct {
+	URL    *string  `json:"url,omitempty" minLength:"1" maxLength:"2048" format:"uri"`
+	Events []string `json:"events,omitempty" minItems:"1" uniqueItems:"true" enum:"task.created,task.updated,task.deleted,task.purged"`
+	Secret *string  `json:"secret,omitempty" minLength:"16" maxLength:"256"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type WebhookIDInput struct {
+	ID string `path:"id"`
+}
+
+type ListDeliveriesInput struct {
+	ID     string `path:"id"`
+	Status string `query:"status" enum:"pending,succeeded,failed" doc:"Only deliveries with this status"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"200" default:"50"`
+}
+
+type ListDeliveriesOutput struct {
+	Body ListDeliveriesResponse
+}
+
+type ListDeliveriesResponse struct {
+	Items []service.Delivery `json:"items"`
+	Count int                `json:"count"`
+}
+
+type DeliveryInput struct {
+	ID         string `path:"id"`
+	DeliveryID string `path:"deliveryId"`
+}
+
+type DeliveryOutput struct {
+	Body service.Delivery
+}
+
+func (i *UpdateWebhookInput) Resolve(ctx huma.Context) []error {
+	if i.Body.URL == nil && i.Body.Events == nil && i.Body.Secret == nil && i.Body.Active == nil {
+		return []error{&huma.ErrorDetail{Message: "at least one field must be provided", Location: "body"}}
+	}
+	return nil
+}
+
+func eventTypes(events []string) []service.EventType {
+	if events == nil {
+		return nil
+	}
+	types := make([]service.EventType, len(events))
+	for i, event := range events {
+		types[i] = service.EventType(event)
+	}
+	return types
+}
+
+func registerWebhookRoutes(api huma.API, svc *service.Service) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-webhook",
+		Method:      http.MethodPost,
+		Path:        "/webhooks",
+		Summary:     "Create a webhook",
+		Description: "Subscribes a URL to task events. Each event is POSTed as JSON with the headers " +
+			"X-Webhook-Event, X-Webhook-Delivery and X-Webhook-Signature, which is sha256= followed by the hex " +
+			"HMAC-SHA256 of the body keyed with the secret. Any status but 2xx is retried with exponential backoff.",
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *CreateWebhookInput) (*WebhookOutput, error) {
+		webhook, err := svc.CreateWebhook(ctx, service.CreateWebhookRequest{
+			URL:    input.Body.URL,
+			Events: eventTypes(input.Body.Events),
+			Secret: input.Body.Secret,
+			Active: input.Body.Active,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &WebhookOutput{Body: WebhookResponse{Webhook: *webhook, Secret: webhook.Secret}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-webhooks",
+		Method:      http.MethodGet,
+		Path:        "/webhooks",
+		Summary:     "List webhooks",
+	}, func(ctx context.Context, input *struct{}) (*ListWebhooksOutput, error) {
+		webhooks, err := svc.ListWebhooks(ctx)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ListWebhooksOutput{Body: ListWebhooksResponse{Items: webhooks, Count: len(webhooks)}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-webhook",
+		Method:      http.MethodGet,
+		Path:        "/webhooks/{id}",
+		Summary:     "Get webhook by ID",
+	}, func(ctx context.Context, input *WebhookIDInput) (*WebhookOutput, error) {
+		webhook, err := svc.GetWebhook(ctx, input.ID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &WebhookOutput{Body: WebhookResponse{Webhook: *webhook}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-webhook",
+		Method:      http.MethodPatch,
+		Path:        "/webhooks/{id}",
+		Summary:     "Update webhook",
+	}, func(ctx context.Context, input *UpdateWebhookInput) (*WebhookOutput, error) {
+		webhook, err := svc.UpdateWebhook(ctx, input.ID, service.UpdateWebhookRequest{
+			URL:    input.Body.URL,
+			Events: eventTypes(input.Body.Events),
+			Secret: input.Body.Secret,
+			Active: input.Body.Active,
+		})
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &WebhookOutput{Body: WebhookResponse{Webhook: *webhook}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-webhook",
+		Method:        http.MethodDelete,
+		Path:          "/webhooks/{id}",
+		Summary:       "Delete webhook",
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *WebhookIDInput) (*struct{}, error) {
+		if err := svc.DeleteWebhook(ctx, input.ID); err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-webhook-deliveries",
+		Method:      http.MethodGet,
+		Path:        "/webhooks/{id}/deliveries",
+		Summary:     "List the deliveries of a webhook, newest first",
+	}, func(ctx context.Context, input *ListDeliveriesInput) (*ListDeliveriesOutput, error) {
+		deliveries, err := svc.ListDeliveries(ctx, input.ID, service.DeliveryStatus(input.Status), input.Limit)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &ListDeliveriesOutput{Body: ListDeliveriesResponse{Items: deliveries, Count: len(deliveries)}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-webhook-delivery",
+		Method:      http.MethodGet,
+		Path:        "/webhooks/{id}/deliveries/{deliveryId}",
+		Summary:     "Get a delivery of a webhook",
+	}, func(ctx context.Context, input *DeliveryInput) (*DeliveryOutput, error) {
+		delivery, err := svc.GetDelivery(ctx, input.ID, input.DeliveryID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &DeliveryOutput{Body: *delivery}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "redeliver-webhook-delivery",
+		Method:      http.MethodPost,
+		Path:        "/webhooks/{id}/deliveries/{deliveryId}:redeliver",
+		Summary:     "Redeliver a delivery",
+		Description: "Queues the event of a succeeded or failed delivery again as a new delivery, " +
+			"sent right away with a fresh set of attempts.",
+		DefaultStatus: http.StatusAccepted,
+	}, func(ctx context.Context, input *DeliveryInput) (*DeliveryOutput, error) {
+		delivery, err := svc.Redeliver(ctx, input.ID, input.DeliveryID)
+		if err != nil {
+			return nil, MapServiceError(ctx, err)
+		}
+		return &DeliveryOutput{Body: *delivery}, nil
+	})
+}
@@ -42,6 +42,19 @@ func FloatEnv(key string, defValue float64) (float64, error) {
 	return strconv.ParseFloat(value, 64)
 }
 
+func FloatListEnv(key, defValue string) ([]float64, error) {
+	parts := SplitCommaList(GetEnv(key, defValue))
+	out := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
 func DurationEnv(key string, defValue time.Duration) (time.Duration, error) {
 	value := GetEnv(key, defValue.String())
 	return time.ParseDuration(value)
@@ -0,0 +1,522 @@
+// Package ical reads and writes the VTODO components of iCalendar
+// (RFC 5545) documents.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const ContentType = "text/calendar"
+
+const (
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusInProcess   = "IN-PROCESS"
+	StatusCompleted   = "COMPLETED"
+	StatusCancelled   = "CANCELLED"
+)
+
+const (
+	maxLineOctets       = 75
+	utcDateTimeLayout   = "20060102T150405Z"
+	localDateTimeLayout = "20060102T150405"
+	dateLayout          = "20060102"
+)
+
+// Todo holds the VTODO properties tasks map to. Priority goes from 1, the
+// highest, to 9; 0 leaves it undefined. Alarm is when the first VALARM
+// triggers.
+type Todo struct {
+	UID         string
+	Stamp       time.Time
+	Summary     string
+	Description string
+	Status      string
+	Priority    int
+	Categories  []string
+	Created     *time.Time
+	Completed   *time.Time
+	Due         *time.Time
+	Alarm       *time.Time
+}
+
+// PropertyError reports a VTODO property that could not be parsed.
+type PropertyError struct {
+	Property string
+	Err      error
+}
+
+func (e *PropertyError) Error() string { return fmt.Sprintf("%s: %v", e.Property, e.Err) }
+func (e *PropertyError) Unwrap() error { return e.Err }
+
+// Writer writes VTODOs into a single VCALENDAR. Times are written in UTC
+// and long lines are folded.
+type Writer struct {
+	w       io.Writer
+	prodID  string
+	started bool
+	err     error
+}
+
+func NewWriter(w io.Writer, prodID string) *Writer {
+	return &Writer{w: w, prodID: prodID}
+}
+
+func (w *Writer) WriteTodo(todo Todo) error {
+	w.begin()
+	stamp := todo.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	w.property("BEGIN", "VTODO")
+	w.property("UID", escapeText(todo.UID))
+	w.property("DTSTAMP", formatTime(stamp))
+	if todo.Created != nil {
+		w.property("CREATED", formatTime(*todo.Created))
+	}
+	w.property("SUMMARY", escapeText(todo.Summary))
+	if todo.Description != "" {
+		w.property("DESCRIPTION", escapeText(todo.Description))
+	}
+	if todo.Status != "" {
+		w.property("STATUS", todo.Status)
+	}
+	if todo.Completed != nil {
+		w.property("COMPLETED", formatTime(*todo.Completed))
+	}
+	if todo.Priority > 0 {
+		w.property("PRIORITY", strconv.Itoa(todo.Priority))
+	}
+	if len(todo.Categories) > 0 {
+		categories := make([]string, len(todo.Categories))
+		for i, category := range todo.Categories {
+			categories[i] = escapeText(category)
+		}
+		w.property("CATEGORIES", strings.Join(categories, ","))
+	}
+	if todo.Due != nil {
+		w.property("DUE", formatTime(*todo.Due))
+	}
+	if todo.Alarm != nil {
+		w.property("BEGIN", "VALARM")
+		w.property("ACTION", "DISPLAY")
+		w.property("DESCRIPTION", escapeText(todo.Summary))
+		w.property("TRIGGER;VALUE=DATE-TIME", formatTime(*todo.Alarm))
+		w.property("END", "VALARM")
+	}
+	w.property("END", "VTODO")
+	return w.err
+}
+
+// Close ends the VCALENDAR, which is empty if no VTODO was written.
+func (w *Writer) Close() error {
+	w.begin()
+	w.property("END", "VCALENDAR")
+	return w.err
+}
+
+func (w *Writer) begin() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.property("BEGIN", "VCALENDAR")
+	w.property("VERSION", "2.0")
+	w.property("PRODID", escapeText(w.prodID))
+}
+
+// property writes one content line, folded after maxLineOctets octets
+// without splitting a UTF-8 sequence.
+func (w *Writer) property(name, value string) {
+	if w.err != nil {
+		return
+	}
+	line := name + ":" + value
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		b.WriteString(line[:n])
+		b.WriteString("\r\n ")
+		line = line[n:]
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	_, w.err = io.WriteString(w.w, b.String())
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(utcDateTimeLayout)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// Decode reads the VTODOs of every VCALENDAR in r; other components are
+// skipped. A VTODO with a malformed property is still returned, with a
+// *PropertyError at the same index of errs, so callers can report it and
+// keep the others. err is only set when r is not a well-formed iCalendar
+// stream.
+func Decode(r io.Reader) (todos []Todo, errs []error, err error) {
+	var (
+		stack   []string
+		todo    *Todo
+		todoErr error
+		alarm   *contentLine
+		start   *time.Time
+	)
+	lines := newLineReader(r)
+	for lines.Next() {
+		line, err := parseLine(lines.Line())
+		if err != nil {
+			if todo == nil {
+				return nil, nil, fmt.Errorf("line %d: %w", lines.Number(), err)
+			}
+			if todoErr == nil {
+				todoErr = err
+			}
+			continue
+		}
+
+		switch line.name {
+		case "BEGIN":
+			component := strings.ToUpper(line.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", lines.Number())
+			}
+			stack = append(stack, component)
+			if component == "VTODO" && len(stack) == 2 {
+				todo, todoErr, alarm, start = &Todo{}, nil, nil, nil
+			}
+		case "END":
+			component := strings.ToUpper(line.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, nil, fmt.Errorf("line %d: unexpected END:%s", lines.Number(), line.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VTODO" && todo != nil {
+				if alarm != nil {
+					if err := setAlarm(todo, *alarm, start); err != nil && todoErr == nil {
+						todoErr = err
+					}
+				}
+				todos = append(todos, *todo)
+				errs = append(errs, todoErr)
+				todo = nil
+			}
+		default:
+			if todo == nil {
+				continue
+			}
+			switch stack[len(stack)-1] {
+			case "VTODO":
+				if line.name == "DTSTART" {
+					t, err := parseTime(line)
+					if err == nil {
+						start = &t
+					} else if todoErr == nil {
+						todoErr = &PropertyError{Property: line.name, Err: err}
+					}
+					continue
+				}
+				if err := setProperty(todo, line); err != nil && todoErr == nil {
+					todoErr = err
+				}
+			case "VALARM":
+				if line.name == "TRIGGER" && alarm == nil {
+					alarm = &line
+				}
+			}
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(stack) > 0 {
+		return nil, nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+	if lines.Number() == 0 {
+		return nil, nil, errors.New("empty iCalendar stream")
+	}
+	return todos, errs, nil
+}
+
+func setProperty(todo *Todo, line contentLine) error {
+	var err error
+	switch line.name {
+	case "UID":
+		todo.UID = unescapeText(line.value)
+	case "DTSTAMP":
+		todo.Stamp, err = parseTime(line)
+	case "SUMMARY":
+		todo.Summary = unescapeText(line.value)
+	case "DESCRIPTION":
+		todo.Description = unescapeText(line.value)
+	case "STATUS":
+		todo.Status = strings.ToUpper(line.value)
+	case "PRIORITY":
+		priority, convErr := strconv.Atoi(line.value)
+		if convErr != nil || priority < 0 || priority > 9 {
+			err = errors.New("must be an integer between 0 and 9")
+		}
+		todo.Priority = priority
+	case "CATEGORIES":
+		for _, category := range splitText(line.value) {
+			if category != "" {
+				todo.Categories = append(todo.Categories, category)
+			}
+		}
+	case "CREATED":
+		todo.Created, err = parseTimePtr(line)
+	case "COMPLETED":
+		todo.Completed, err = parseTimePtr(line)
+	case "DUE":
+		todo.Due, err = parseTimePtr(line)
+	}
+	if err != nil {
+		return &PropertyError{Property: line.name, Err: err}
+	}
+	return nil
+}
+
+// setAlarm resolves the trigger of a VALARM. A relative trigger counts
+// from DTSTART, or from DUE when RELATED=END or the VTODO has no DTSTART.
+func setAlarm(todo *Todo, trigger contentLine, start *time.Time) error {
+	if trigger.params["VALUE"] == "DATE-TIME" {
+		t, err := parseTime(trigger)
+		if err != nil {
+			return &PropertyError{Property: trigger.name, Err: err}
+		}
+		todo.Alarm = &t
+		return nil
+	}
+
+	offset, err := parseDuration(trigger.value)
+	if err != nil {
+		return &PropertyError{Property: trigger.name, Err: err}
+	}
+	base := start
+	if trigger.params["RELATED"] == "END" || base == nil {
+		base = todo.Due
+	}
+	if base != nil {
+		t := base.Add(offset)
+		todo.Alarm = &t
+	}
+	return nil
+}
+
+func parseTimePtr(line contentLine) (*time.Time, error) {
+	t, err := parseTime(line)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseTime parses a DATE or DATE-TIME value. Local times use their TZID
+// when Go knows it and are otherwise read as UTC, like floating times.
+func parseTime(line contentLine) (time.Time, error) {
+	value := line.value
+	if line.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		return time.Parse(dateLayout, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(utcDateTimeLayout, value)
+	}
+	loc := time.UTC
+	if tzid := line.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localDateTimeLayout, value, loc)
+	return t.UTC(), err
+}
+
+// parseDuration parses a DURATION value such as -PT15M or P1DT12H.
+func parseDuration(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration %q", value)
+	s, sign := value, time.Duration(1)
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		s, sign = rest, -1
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+	s, ok := strings.CutPrefix(s, "P")
+	if !ok || s == "" {
+		return 0, invalid
+	}
+
+	units := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var d time.Duration
+	n, digits := 0, false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			n, digits = n*10+int(c-'0'), true
+		case c == 'T' && !digits && units['H'] == 0:
+			units = map[rune]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		case digits && units[c] != 0:
+			d += time.Duration(n) * units[c]
+			n, digits = 0, false
+		default:
+			return 0, invalid
+		}
+	}
+	if digits {
+		return 0, invalid
+	}
+	return sign * d, nil
+}
+
+// splitText splits a list of TEXT values on unescaped commas and
+// unescapes each value.
+func splitText(value string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescapeText(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescapeText(value[start:]))
+}
+
+func unescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted, and quoted values may contain ":" and
+// ";".
+func parseLine(line string) (contentLine, error) {
+	var fields []string
+	start, quoted := 0, false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				fields = append(fields, line[start:i])
+				start = i + 1
+			}
+		case ':':
+			if !quoted {
+				fields = append(fields, line[start:i])
+				colon = i
+			}
+		}
+	}
+	if colon <= 0 || fields[0] == "" {
+		return contentLine{}, fmt.Errorf("malformed content line %q", line)
+	}
+
+	parsed := contentLine{name: strings.ToUpper(fields[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range fields[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return contentLine{}, fmt.Errorf("malformed parameter %q", param)
+		}
+		value = strings.Trim(value, `"`)
+		if key = strings.ToUpper(key); key == "VALUE" || key == "RELATED" {
+			value = strings.ToUpper(value)
+		}
+		parsed.params[key] = value
+	}
+	return parsed, nil
+}
+
+// lineReader returns the unfolded content lines of r.
+type lineReader struct {
+	scanner *bufio.Scanner
+	line    string
+	next    *string
+	number  int
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &lineReader{scanner: scanner}
+}
+
+func (l *lineReader) Next() bool {
+	var b strings.Builder
+	if l.next != nil {
+		b.WriteString(*l.next)
+		l.next = nil
+	}
+	for l.scanner.Scan() {
+		text := strings.TrimSuffix(l.scanner.Text(), "\r")
+		if text != "" && (text[0] == ' ' || text[0] == '\t') {
+			b.WriteString(text[1:])
+			continue
+		}
+		if b.Len() == 0 {
+			if text == "" {
+				continue
+			}
+			b.WriteString(text)
+			continue
+		}
+		l.next = &text
+		break
+	}
+	if b.Len() == 0 {
+		return false
+	}
+	l.line = b.String()
+	l.number++
+	return true
+}
+
+func (l *lineReader) Line() string { return l.line }
+
+// Number is the count of content lines read so far.
+func (l *lineReader) Number() int { return l.number }
+
+func (l *lineReader) Err() error { return l.scanner.Err() }
@@ -17,31 +17,38 @@ import (
 type Mode string
 
 const (
-	ModeAppend  Mode = "append"
-	ModeReplace Mode = "replace"
-	ModeUpsert  Mode = "upsert"
+	ModeAppend   Mode = "append"
+	ModeReplace  Mode = "replace"
+	ModeUpsert   Mode = "upsert"
 	ModeMaintain Mode = "maintain"
 )
 
 const (
-	defaultCreatedAtDays  = 30
-	seedKeyIndexName      = "seedKey_unique"
-	defaultSeedVersionFmt = "seed-%d"
+	defaultCreatedAtDays = 30
+	maxDueAfterDays      = 21
+	seedKeyIndexName     = "seedKey_unique"
+	// defaultSeedVersionFmt changes whenever buildDocs draws differently,
+	// so that upserts do not rewrite tasks seeded by an older generator.
+	defaultSeedVersionFmt = "seed-v2-%d"
 )
 
 type Config struct {
-	Count          int
-	RandomSeed     int64
-	Mode           Mode
-	SeedVersion    string
-	TitlePrefix    string
-	Tags           []string
-	DoneRatio      float64
-	TagCountMin    int
-	TagCountMax    int
-	CreatedAtStart time.Time
-	CreatedAtEnd   time.Time
-	Timeout        time.Duration
+	Count       int
+	RandomSeed  int64
+	Mode        Mode
+	SeedVersion string
+	TitlePrefix string
+	Tags        []string
+	DoneRatio   float64
+	DueRatio    float64
+	// PriorityWeights are relative weights for low, normal, high and urgent,
+	// in that order. When empty, seeded tasks get no priority (read as normal).
+	PriorityWeights []float64
+	TagCountMin     int
+	TagCountMax     int
+	CreatedAtStart  time.Time
+	CreatedAtEnd    time.Time
+	Timeout         time.Duration
 }
 
 type Result struct {
@@ -110,6 +117,24 @@ func normalizeConfig(cfg Config) (Config, error) {
 	if cfg.DoneRatio < 0 || cfg.DoneRatio > 1 {
 		return Config{}, errors.New("seed done ratio must be between 0 and 1")
 	}
+	if cfg.DueRatio < 0 || cfg.DueRatio > 1 {
+		return Config{}, errors.New("seed due ratio must be between 0 and 1")
+	}
+	if len(cfg.PriorityWeights) > 0 {
+		if len(cfg.PriorityWeights) != len(priorities) {
+			return Config{}, fmt.Errorf("seed priority weights must have %d values (%s)", len(priorities), strings.Join(priorities, ","))
+		}
+		total := 0.0
+		for _, weight := range cfg.PriorityWeights {
+			if weight < 0 {
+				return Config{}, errors.New("seed priority weights must be non-negative")
+			}
+			total += weight
+		}
+		if total == 0 {
+			return Config{}, errors.New("seed priority weights must not all be zero")
+		}
+	}
 	if cfg.TagCountMin < 0 || cfg.TagCountMax < 0 {
 		return Config{}, errors.New("seed tag counts must be non-negative")
 	}
@@ -264,16 +289,28 @@ func buildDocs(rnd *rand.Rand, cfg Config, count int, seedVersion, seedKeyPrefix
 		if doneOverride != nil {
 			doneValue = *doneOverride
 		}
+		createdAt := randomTime(rnd, cfg.CreatedAtStart, cfg.CreatedAtEnd)
+		// Optional fields draw only when enabled, so that they leave the
+		// sequence of the others alone.
 		doc := bson.M{
 			"title":       title,
 			"done":        doneValue,
 			"tags":        pickTags(rnd, cfg.Tags, cfg.TagCountMin, cfg.TagCountMax),
-			"createdAt":   randomTime(rnd, cfg.CreatedAtStart, cfg.CreatedAtEnd),
+			"createdAt":   createdAt,
 			"seedKey":     seedKey(seedVersion, seedKeyPrefix, i),
 			"seedVersion": seedVersion,
 			"seedIndex":   i,
 			"seededAt":    now,
 		}
+		if len(cfg.PriorityWeights) > 0 {
+			doc["priority"] = pickPriority(rnd, cfg.PriorityWeights)
+		}
+		if cfg.DueRatio > 0 && rnd.Float64() < cfg.DueRatio {
+			doc["dueAt"] = randomDueAt(rnd, createdAt)
+		}
+		if doneValue {
+			doc["completedAt"] = randomTime(rnd, createdAt, now)
+		}
 		docs = append(docs, doc)
 	}
 	return docs
@@ -327,6 +364,30 @@ func randomTime(rnd *rand.Rand, start, end time.Time) time.Time {
 	return start.Add(offset)
 }
 
+func pickPriority(rnd *rand.Rand, weights []float64) string {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	target := rnd.Float64() * total
+	for i, weight := range weights {
+		if target < weight {
+			return priorities[i]
+		}
+		target -= weight
+	}
+	return priorities[len(priorities)-1]
+}
+
+// randomDueAt picks a deadline between one and maxDueAfterDays days after
+// creation, rounded to the hour like a human-entered due date.
+func randomDueAt(rnd *rand.Rand, createdAt time.Time) time.Time {
+	days := 1 + rnd.Intn(maxDueAfterDays)
+	return createdAt.AddDate(0, 0, days).Truncate(time.Hour)
+}
+
+var priorities = []string{"low", "normal", "high", "urgent"}
+
 var adjectives = []string{
 	"Quick",
 	"Bright",
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const MaxBatchOperations = 500
+
+// ErrBatchAborted is the result of the operations of an atomic batch that
+// were not applied because another operation failed.
+var ErrBatchAborted = errors.New("not applied because another operation of the atomic batch failed")
+
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// batchEvents is the event each kind of batch write publishes.
+var batchEvents = map[BatchOpKind]EventType{
+	BatchCreate: EventTaskCreated,
+	BatchUpdate: EventTaskUpdated,
+	BatchDelete: EventTaskDeleted,
+}
+
+// BatchOperation is one operation of a batch. Create is used by creates,
+// ID and Update by updates, ID and IfVersion by deletes.
+type BatchOperation struct {
+	Kind      BatchOpKind
+	ID        string
+	Create    CreateTaskRequest
+	Update    UpdateTaskRequest
+	IfVersion *int64
+}
+
+// BatchResult is the outcome of one operation. Task is the created or
+// updated task and is nil for deletes and failed operations.
+type BatchResult struct {
+	Task *Task
+	Err  error
+}
+
+// BatchWrite is a validated batch operation handed to the repository.
+// IfVersion, when set, must still match the task's version at write time.
+type BatchWrite struct {
+	Kind      BatchOpKind
+	ID        string
+	Task      Task
+	Update    UpdateTaskRequest
+	IfVersion *int64
+}
+
+// BatchRepository applies validated writes in a single round trip.
+// ApplyBatch sets the ID of every created task in writes and returns one
+// error per write, nil for the writes that were applied. Atomic batches
+// apply every write or none: when one fails, the others report
+// ErrBatchAborted.
+type BatchRepository interface {
+	ApplyBatch(ctx context.Context, writes []BatchWrite, atomic bool) ([]error, error)
+}
+
+// Batch validates every operation like Create, Update and Delete do and
+// applies the valid ones together. In an atomic batch a single invalid
+// operation aborts the whole batch.
+//
+// Updates and deletes are guarded by the version their task was read at.
+// Those that miss because the task changed in the meantime are validated
+// and applied again, like Update does, up to patchAttempts times, unless
+// the operation carried its own IfVersion; in an atomic batch the whole
+// batch is retried.
+func (s *Service) Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchOperations {
+		return nil, &ValidationError{
+			Field:   "operations",
+			Message: fmt.Sprintf("operations must contain between 1 and %d items", MaxBatchOperations),
+			Value:   len(ops),
+		}
+	}
+
+	results := make([]BatchResult, len(ops))
+	pending := make([]int, len(ops))
+	for i := range pending {
+		pending[i] = i
+	}
+	for attempt := 1; ; attempt++ {
+		stale, err := s.applyBatchOps(ctx, ops, pending, results, atomic)
+		if err != nil {
+			return nil, err
+		}
+		if len(stale) == 0 || attempt == patchAttempts {
+			return results, nil
+		}
+		pending = stale
+	}
+}
+
+// applyBatchOps applies the operations of ops listed in pending, stores
+// their outcome in results and returns the operations to try again
+// because their task changed since it was read.
+func (s *Service) applyBatchOps(ctx context.Context, ops []BatchOperation, pending []int, results []BatchResult, atomic bool) ([]int, error) {
+	subset := make([]BatchOperation, len(pending))
+	for j, i := range pending {
+		subset[j] = ops[i]
+	}
+	targets, targetErrs, err := s.batchTargets(ctx, subset)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := make([]BatchWrite, 0, len(pending))
+	writeOps := make([]int, 0, len(pending))
+	failed := false
+	for _, i := range pending {
+		op := ops[i]
+		results[i] = BatchResult{}
+		write, err := s.prepareBatchWrite(ctx, op, targets[op.ID], targetErrs[op.ID])
+		if err != nil {
+			results[i].Err = err
+			failed = true
+			continue
+		}
+		writes = append(writes, write)
+		writeOps = append(writeOps, i)
+	}
+	if atomic && failed {
+		for _, i := range pending {
+			if results[i].Err == nil {
+				results[i].Err = ErrBatchAborted
+			}
+		}
+		return nil, nil
+	}
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	writeErrs, err := s.repo.ApplyBatch(ctx, writes, atomic)
+	if err != nil {
+		return nil, err
+	}
+
+	// A write the client did not guard with its own IfVersion missed only
+	// because its task changed after batchTargets read it.
+	var stale []int
+	retryAll := atomic
+	for j := range writes {
+		i := writeOps[j]
+		var mismatch *VersionMismatchError
+		switch {
+		case errors.As(writeErrs[j], &mismatch) && ops[i].IfVersion == nil && ops[i].Update.IfVersion == nil:
+			stale = append(stale, i)
+		case writeErrs[j] != nil && !errors.Is(writeErrs[j], ErrBatchAborted):
+			retryAll = false
+		}
+	}
+	if retryAll && len(stale) > 0 {
+		// Nothing was applied, so the whole batch is tried again.
+		for j, i := range writeOps {
+			results[i].Err = writeErrs[j]
+		}
+		return pending, nil
+	}
+
+	var changed []string
+	for j, write := range writes {
+		if writeErrs[j] == nil && write.Kind != BatchDelete {
+			changed = append(changed, write.ID)
+		}
+	}
+	after := map[string]*Task{}
+	if len(changed) > 0 {
+		tasks, err := s.repo.GetMany(ctx, changed)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tasks {
+			after[tasks[i].ID] = &tasks[i]
+		}
+	}
+
+	var events []Event
+	for j, write := range writes {
+		i := writeOps[j]
+		if writeErrs[j] != nil {
+			results[i].Err = writeErrs[j]
+			continue
+		}
+		results[i].Task = after[write.ID]
+		if write.Kind == BatchUpdate && results[i].Task != nil {
+			s.recordRevision(ctx, targets[write.ID], results[i].Task)
+		}
+		events = append(events, s.newEvent(batchEvents[write.Kind], write.ID, results[i].Task))
+	}
+	s.publish(ctx, events...)
+	return stale, nil
+}
+
+// batchTargets loads the tasks that updates and deletes refer to with a
+// single query. Only when an ID is malformed does it fall back to loading
+// them one by one, to report the error on the right operations.
+func (s *Service) batchTargets(ctx context.Context, ops []BatchOperation) (map[string]*Task, map[string]error, error) {
+	var ids []string
+	seen := map[string]struct{}{}
+	for _, op := range ops {
+		if op.Kind == BatchCreate {
+			continue
+		}
+		if _, ok := seen[op.ID]; ok {
+			continue
+		}
+		seen[op.ID] = struct{}{}
+		ids = append(ids, op.ID)
+	}
+
+	targets := map[string]*Task{}
+	targetErrs := map[string]error{}
+	if len(ids) == 0 {
+		return targets, targetErrs, nil
+	}
+	tasks, err := s.repo.GetMany(ctx, ids)
+	switch {
+	case errors.Is(err, ErrInvalidID):
+		for _, id := range ids {
+			task, err := s.repo.Get(ctx, id)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidID) && !errors.Is(err, ErrNotFound) {
+					return nil, nil, err
+				}
+				targetErrs[id] = err
+				continue
+			}
+			targets[id] = task
+		}
+		return targets, targetErrs, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	for i := range tasks {
+		targets[tasks[i].ID] = &tasks[i]
+	}
+	for _, id := range ids {
+		if _, ok := targets[id]; !ok {
+			targetErrs[id] = ErrNotFound
+		}
+	}
+	return targets, targetErrs, nil
+}
+
+func (s *Service) prepareBatchWrite(ctx context.Context, op BatchOperation, target *Task, targetErr error) (BatchWrite, error) {
+	switch op.Kind {
+	case BatchCreate:
+		task, err := s.newTask(ctx, op.Create)
+		if err != nil {
+			return BatchWrite{}, err
+		}
+		return BatchWrite{Kind: BatchCreate, Task: *task}, nil
+	case BatchUpdate:
+		req := op.Update
+		if err := validateUpdate(&req); err != nil {
+			return BatchWrite{}, err
+		}
+		if targetErr != nil {
+			return BatchWrite{}, targetErr
+		}
+		if err := s.checkUpdate(ctx, target, &req); err != nil {
+			return BatchWrite{}, err
+		}
+		return BatchWrite{Kind: BatchUpdate, ID: op.ID, Update: req, IfVersion: &target.Version}, nil
+	case BatchDelete:
+		if targetErr != nil {
+			return BatchWrite{}, targetErr
+		}
+		if op.IfVersion != nil && *op.IfVersion != target.Version {
+			return BatchWrite{}, &VersionMismatchError{Current: target.Version}
+		}
+		return BatchWrite{Kind: BatchDelete, ID: op.ID, IfVersion: &target.Version}, nil
+	default:
+		return BatchWrite{}, &ValidationError{
+			Field:   "op",
+			Message: "op must be one of create, update, delete",
+			Value:   op.Kind,
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// bulkPageSize is how many tasks a bulk change writes per round trip; it
+// bounds the memory the change holds at once.
+const bulkPageSize = MaxBatchOperations
+
+// BulkResult reports a change applied to every task matching a filter.
+// Matched counts the tasks the filter selected, Modified the tasks the
+// change was written to. A dry run only counts the matching tasks.
+type BulkResult struct {
+	Matched  int64
+	Modified int64
+	DryRun   bool
+}
+
+// UpdateWhere applies req to every task matching filter. Unless dryRun is
+// set, confirm must equal the number of matching tasks, so a mistyped or
+// empty filter cannot rewrite the whole collection. Every task changed
+// gets a revision and a task.updated event, like with Update.
+func (s *Service) UpdateWhere(ctx context.Context, filter TaskFilter, req UpdateTaskRequest, confirm *int64, dryRun bool) (*BulkResult, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	if err := validateUpdate(&req); err != nil {
+		return nil, err
+	}
+	if (req.DueAt == nil) != (req.RemindAt == nil) {
+		// Each task has its own counterpart, so the pair is only checked
+		// when both are set.
+		return nil, &ValidationError{
+			Field:   "dueAt",
+			Message: "dueAt and remindAt must be set together in bulk updates",
+		}
+	}
+	req.DueAt, req.RemindAt = utcPtr(req.DueAt), utcPtr(req.RemindAt)
+	if err := validateSchedule(req.DueAt, req.RemindAt); err != nil {
+		return nil, err
+	}
+	if req.ProjectID != nil {
+		if err := s.ensureProject(ctx, *req.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	matched, err := s.confirmBulk(ctx, filter, confirm, dryRun)
+	if err != nil || dryRun {
+		return &BulkResult{Matched: matched, DryRun: dryRun}, err
+	}
+	if req.Done != nil && *req.Done && (filter.Blocked == nil || *filter.Blocked) {
+		blocked := true
+		blockedFilter := filter
+		blockedFilter.Blocked = &blocked
+		count, err := s.repo.Count(ctx, blockedFilter)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, &ConflictError{Message: fmt.Sprintf("%d matching task(s) are blocked by open tasks", count)}
+		}
+	}
+
+	return s.rewriteWhere(ctx, filter, func(task Task) (BatchWrite, bool) {
+		updated := applyUpdate(task, req)
+		if len(diffTasks(&task, &updated)) == 0 {
+			return BatchWrite{}, false
+		}
+		return BatchWrite{Kind: BatchUpdate, Update: req}, true
+	})
+}
+
+// DeleteWhere moves every task matching filter to the trash, with the same
+// confirmation rules as UpdateWhere.
+func (s *Service) DeleteWhere(ctx context.Context, filter TaskFilter, confirm *int64, dryRun bool) (*BulkResult, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	matched, err := s.confirmBulk(ctx, filter, confirm, dryRun)
+	if err != nil || dryRun {
+		return &BulkResult{Matched: matched, DryRun: dryRun}, err
+	}
+	return s.deleteWhere(ctx, filter)
+}
+
+func (s *Service) deleteWhere(ctx context.Context, filter TaskFilter) (*BulkResult, error) {
+	return s.rewriteWhere(ctx, filter, func(Task) (BatchWrite, bool) {
+		return BatchWrite{Kind: BatchDelete}, true
+	})
+}
+
+// rewriteWhere writes the change returned for each task matching filter,
+// streaming the tasks and writing them a page at a time. Every write is
+// guarded by the version its task was read at, so a task changed in the
+// meantime is never overwritten blindly; when some were, the filter is
+// run again to pick up those that still match, up to patchAttempts times.
+// Each write applied publishes the event of its kind.
+func (s *Service) rewriteWhere(ctx context.Context, filter TaskFilter, change func(task Task) (BatchWrite, bool)) (*BulkResult, error) {
+	result := &BulkResult{}
+	for pass := 1; ; pass++ {
+		matched, modified, stale, err := s.rewritePass(ctx, filter, change)
+		if pass == 1 {
+			result.Matched = matched
+		}
+		result.Modified += modified
+		if err != nil {
+			return nil, err
+		}
+		if stale == 0 || pass == patchAttempts {
+			return result, nil
+		}
+	}
+}
+
+// rewritePass makes one pass of rewriteWhere and reports how many tasks
+// matched, how many writes applied and how many missed because their
+// task had changed.
+func (s *Service) rewritePass(ctx context.Context, filter TaskFilter, change func(task Task) (BatchWrite, bool)) (matched, modified, stale int64, err error) {
+	cursor, err := s.repo.Export(ctx, filter)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	page := make([]Task, 0, bulkPageSize)
+	flush := func() error {
+		applied, missed, err := s.rewritePage(ctx, page, change)
+		modified += applied
+		stale += missed
+		page = page[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		matched++
+		page = append(page, cursor.Task())
+		if len(page) == bulkPageSize {
+			if err := flush(); err != nil {
+				return matched, modified, stale, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return matched, modified, stale, err
+	}
+	if len(page) > 0 {
+		err = flush()
+	}
+	return matched, modified, stale, err
+}
+
+func (s *Service) rewritePage(ctx context.Context, tasks []Task, change func(task Task) (BatchWrite, bool)) (applied, stale int64, err error) {
+	writes := make([]BatchWrite, 0, len(tasks))
+	for _, task := range tasks {
+		write, ok := change(task)
+		if !ok {
+			continue
+		}
+		version := task.Version
+		write.ID, write.IfVersion = task.ID, &version
+		writes = append(writes, write)
+	}
+	if len(writes) == 0 {
+		return 0, 0, nil
+	}
+
+	errs, err := s.repo.ApplyBatch(ctx, writes, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		updated []string
+		events  []Event
+		failed  error
+	)
+	for j, err := range errs {
+		var mismatch *VersionMismatchError
+		switch {
+		case err == nil:
+			applied++
+			if writes[j].Kind == BatchUpdate {
+				updated = append(updated, writes[j].ID)
+			} else {
+				events = append(events, s.newEvent(batchEvents[writes[j].Kind], writes[j].ID, nil))
+			}
+		case errors.As(err, &mismatch):
+			stale++
+		case errors.Is(err, ErrNotFound):
+			// Deleted since it was read.
+		default:
+			failed = err
+		}
+	}
+	// The tasks read back for their revisions are the payloads of their
+	// events.
+	after := s.recordRevisions(ctx, tasks, updated)
+	for i := range after {
+		events = append(events, s.newEvent(EventTaskUpdated, after[i].ID, &after[i]))
+	}
+	s.publish(ctx, events...)
+	return applied, stale, failed
+}
+
+// applyUpdate returns task with the fields set in req changed, the way
+// the repository writes them.
+func applyUpdate(task Task, req UpdateTaskRequest) Task {
+	if req.Title != nil {
+		task.Title = *req.Title
+	}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.Done != nil {
+		task.Done = *req.Done
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+	if req.ProjectID != nil {
+		task.ProjectID = *req.ProjectID
+	}
+	if req.Tags != nil {
+		task.Tags = slices.Clone(*req.Tags)
+	}
+	if req.DueAt != nil {
+		task.DueAt = req.DueAt
+	}
+	if req.RemindAt != nil {
+		task.RemindAt = req.RemindAt
+	}
+	return task
+}
+
+// confirmBulk counts the tasks matching filter and, unless dryRun is set,
+// checks the count against confirm.
+func (s *Service) confirmBulk(ctx context.Context, filter TaskFilter, confirm *int64, dryRun bool) (int64, error) {
+	matched, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return matched, nil
+	}
+	if confirm == nil {
+		return 0, &ValidationError{
+			Field:   "confirm",
+			Message: "confirm must be set to the number of matching tasks, use dryRun to preview it",
+		}
+	}
+	if *confirm != matched {
+		return 0, &ConflictError{Message: fmt.Sprintf("filter matches %d task(s), not %d", matched, *confirm)}
+	}
+	return matched, nil
+}
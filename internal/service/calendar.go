@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MaxUIDLength = 255
+	// calendarUIDSuffix ends the UIDs derived from task IDs.
+	calendarUIDSuffix = "@task-api"
+)
+
+// CalendarRepository finds tasks by the iCalendar UID they were imported
+// with.
+type CalendarRepository interface {
+	// TakenUIDs returns which of uids belong to a task, trashed tasks
+	// included.
+	TakenUIDs(ctx context.Context, uids []string) ([]string, error)
+	// ExistingIDs returns which of ids belong to a task, trashed tasks
+	// included.
+	ExistingIDs(ctx context.Context, ids []string) ([]string, error)
+	// GetByUID returns the live task with the stored UID uid.
+	GetByUID(ctx context.Context, uid string) (*Task, error)
+	// ChangeCount returns a counter that grows with every task write and
+	// never goes back.
+	ChangeCount(ctx context.Context) (int64, error)
+}
+
+// CalendarUID returns the iCalendar UID of task: the UID it was imported
+// with or, for tasks created here, one derived from its ID, so that every
+// export gives a task the same UID.
+func CalendarUID(task Task) string {
+	if task.UID != "" {
+		return task.UID
+	}
+	return task.ID + calendarUIDSuffix
+}
+
+// TaskByUID returns the live task whose CalendarUID is uid. A derived UID
+// that matches no task ID can still be stored by a task imported from
+// another instance, or from before its original was purged.
+func (s *Service) TaskByUID(ctx context.Context, uid string) (*Task, error) {
+	if id, ok := taskIDFromUID(uid); ok {
+		task, err := s.repo.Get(ctx, id)
+		if !errors.Is(err, ErrNotFound) {
+			return task, err
+		}
+	}
+	return s.repo.GetByUID(ctx, uid)
+}
+
+// ReplaceTask overwrites the task id with the fields of req, the way a
+// CalDAV PUT replaces a resource: optional fields req leaves empty are
+// removed. The project, checklist and dependencies of the task are kept,
+// since calendars do not carry them. It validates like MergePatch.
+func (s *Service) ReplaceTask(ctx context.Context, id string, req CreateTaskRequest, ifVersion *int64) (*Task, error) {
+	patch := map[string]any{
+		"title":       req.Title,
+		"description": nil,
+		"done":        req.Done != nil && *req.Done,
+		"priority":    string(PriorityNormal),
+		"tags":        nil,
+		"dueAt":       nil,
+		"remindAt":    nil,
+	}
+	if req.Description != "" {
+		patch["description"] = req.Description
+	}
+	if req.Priority != "" {
+		patch["priority"] = string(req.Priority)
+	}
+	if len(req.Tags) > 0 {
+		tags := make([]any, len(req.Tags))
+		for i, tag := range req.Tags {
+			tags[i] = tag
+		}
+		patch["tags"] = tags
+	}
+	if req.DueAt != nil {
+		patch["dueAt"] = req.DueAt.UTC().Format(time.RFC3339)
+	}
+	if req.RemindAt != nil {
+		patch["remindAt"] = req.RemindAt.UTC().Format(time.RFC3339)
+	}
+	return s.MergePatch(ctx, id, patch, ifVersion)
+}
+
+// CalendarTag changes whenever a task is written, and never takes a value
+// it had before. CalDAV clients compare it to skip syncing an unchanged
+// collection.
+func (s *Service) CalendarTag(ctx context.Context) (string, error) {
+	changes, err := s.repo.ChangeCount(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(changes, 10), nil
+}
+
+// taskIDFromUID returns the ID of the task a derived UID was made from.
+func taskIDFromUID(uid string) (string, bool) {
+	id, ok := strings.CutSuffix(uid, calendarUIDSuffix)
+	if !ok || len(id) != 24 || id != strings.ToLower(id) {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// takenUIDs returns which of uids already belong to a task, trashed tasks
+// included, either as the UID it was imported with or as the UID derived
+// from its ID.
+func (s *Service) takenUIDs(ctx context.Context, uids []string) (map[string]bool, error) {
+	var stored, ids []string
+	for _, uid := range uids {
+		if uid == "" {
+			continue
+		}
+		stored = append(stored, uid)
+		if id, ok := taskIDFromUID(uid); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	taken := map[string]bool{}
+	if len(stored) > 0 {
+		found, err := s.repo.TakenUIDs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range found {
+			taken[uid] = true
+		}
+	}
+	if len(ids) > 0 {
+		found, err := s.repo.ExistingIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			taken[id+calendarUIDSuffix] = true
+		}
+	}
+	return taken, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MaxChecklistItems      = 100
+	MaxChecklistTextLength = 500
+)
+
+var ErrChecklistItemNotFound = errors.New("checklist item not found")
+
+// ChecklistItem is an entry of a task's ordered checklist.
+type ChecklistItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func NewChecklistProgress(items []ChecklistItem) ChecklistProgress {
+	progress := ChecklistProgress{Total: len(items)}
+	for _, item := range items {
+		if item.Done {
+			progress.Done++
+		}
+	}
+	return progress
+}
+
+// ChecklistRepository performs checklist changes as single atomic updates
+// of the task document.
+type ChecklistRepository interface {
+	AddChecklistItem(ctx context.Context, taskID, text string, position *int) (*Task, error)
+	ToggleChecklistItem(ctx context.Context, taskID, itemID string) (*Task, error)
+	ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) (*Task, error)
+	DeleteChecklistItem(ctx context.Context, taskID, itemID string) (*Task, error)
+}
+
+func (s *Service) AddChecklistItem(ctx context.Context, taskID, text string, position *int) (*Task, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > MaxChecklistTextLength {
+		return nil, &ValidationError{
+			Field:   "text",
+			Message: fmt.Sprintf("text must be between 1 and %d characters", MaxChecklistTextLength),
+			Value:   text,
+		}
+	}
+	if position != nil && *position < 0 {
+		return nil, &ValidationError{
+			Field:   "position",
+			Message: "position must not be negative",
+			Value:   *position,
+		}
+	}
+	task, err := s.repo.AddChecklistItem(ctx, taskID, text, position)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
+
+func (s *Service) ToggleChecklistItem(ctx context.Context, taskID, itemID string) (*Task, error) {
+	task, err := s.repo.ToggleChecklistItem(ctx, taskID, itemID)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
+
+// ReorderChecklist sets the checklist order. itemIDs must name every
+// existing item exactly once.
+func (s *Service) ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) (*Task, error) {
+	seen := make(map[string]struct{}, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if _, dup := seen[itemID]; dup {
+			return nil, &ValidationError{
+				Field:   "itemIds",
+				Message: fmt.Sprintf("duplicate checklist item %q", itemID),
+				Value:   itemIDs,
+			}
+		}
+		seen[itemID] = struct{}{}
+	}
+	task, err := s.repo.ReorderChecklist(ctx, taskID, itemIDs)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
+
+func (s *Service) DeleteChecklistItem(ctx context.Context, taskID, itemID string) (*Task, error) {
+	task, err := s.repo.DeleteChecklistItem(ctx, taskID, itemID)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
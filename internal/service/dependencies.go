@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxBlockers caps the blockedBy list of a single task.
+const MaxBlockers = 50
+
+// DependencyRepository stores blocked-by links between tasks.
+type DependencyRepository interface {
+	AddBlocker(ctx context.Context, taskID, blockerID string) (*Task, error)
+	RemoveBlocker(ctx context.Context, taskID, blockerID string) (*Task, error)
+	// GetMany returns the tasks with the given IDs that exist and are not in
+	// the trash, in no particular order.
+	GetMany(ctx context.Context, ids []string) ([]Task, error)
+}
+
+// AddBlocker records that taskID is blocked by blockerID. Links that would
+// close a cycle are rejected, also when the cycle is closed by concurrent
+// requests.
+func (s *Service) AddBlocker(ctx context.Context, taskID, blockerID string) (*Task, error) {
+	if taskID == blockerID {
+		return nil, &ValidationError{
+			Field:   "taskId",
+			Message: "a task cannot block itself",
+			Value:   blockerID,
+		}
+	}
+	task, err := s.repo.Get(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range task.BlockedBy {
+		if existing == blockerID {
+			return task, nil
+		}
+	}
+	if len(task.BlockedBy) >= MaxBlockers {
+		return nil, &ValidationError{
+			Field:   "taskId",
+			Message: fmt.Sprintf("a task can have at most %d blockers", MaxBlockers),
+		}
+	}
+	if _, err := s.repo.Get(ctx, blockerID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, &ValidationError{Field: "taskId", Message: "blocking task not found", Value: blockerID}
+		}
+		return nil, err
+	}
+
+	cycle, err := s.reachable(ctx, blockerID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle {
+		return nil, &ConflictError{Message: "dependency would create a cycle"}
+	}
+
+	updated, err := s.repo.AddBlocker(ctx, taskID, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	// The walk and the write are not atomic, and a transaction would not
+	// help: concurrent links going opposite ways write different tasks, so
+	// both would commit. Of the writes closing a cycle, the last one to land
+	// sees the whole cycle when it walks again, and takes its link back.
+	cycle, err = s.reachable(ctx, blockerID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle {
+		if _, err := s.repo.RemoveBlocker(ctx, taskID, blockerID); err != nil {
+			return nil, err
+		}
+		return nil, &ConflictError{Message: "dependency would create a cycle"}
+	}
+	return s.publishTask(ctx, EventTaskUpdated, updated, nil)
+}
+
+func (s *Service) RemoveBlocker(ctx context.Context, taskID, blockerID string) (*Task, error) {
+	task, err := s.repo.RemoveBlocker(ctx, taskID, blockerID)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
+
+// Blockers returns the tasks that block taskID.
+func (s *Service) Blockers(ctx context.Context, taskID string) ([]Task, error) {
+	task, err := s.repo.Get(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(task.BlockedBy) == 0 {
+		return []Task{}, nil
+	}
+	return s.repo.GetMany(ctx, task.BlockedBy)
+}
+
+// reachable walks blockedBy links breadth-first from start and reports
+// whether target is among the transitive blockers.
+func (s *Service) reachable(ctx context.Context, start, target string) (bool, error) {
+	visited := map[string]struct{}{start: {}}
+	frontier := []string{start}
+	for len(frontier) > 0 {
+		tasks, err := s.repo.GetMany(ctx, frontier)
+		if err != nil {
+			return false, err
+		}
+		frontier = frontier[:0]
+		for _, task := range tasks {
+			for _, next := range task.BlockedBy {
+				if next == target {
+					return true, nil
+				}
+				if _, seen := visited[next]; seen {
+					continue
+				}
+				visited[next] = struct{}{}
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return false, nil
+}
+
+// ensureUnblocked rejects completing task while any of its blockers is
+// still open.
+func (s *Service) ensureUnblocked(ctx context.Context, task *Task) error {
+	if len(task.BlockedBy) == 0 {
+		return nil
+	}
+	blockers, err := s.repo.GetMany(ctx, task.BlockedBy)
+	if err != nil {
+		return err
+	}
+	open := 0
+	for _, blocker := range blockers {
+		if !blocker.Done {
+			open++
+		}
+	}
+	if open > 0 {
+		return &ConflictError{Message: fmt.Sprintf("task is blocked by %d open task(s)", open)}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import "context"
+
+// TaskCursor iterates over tasks one at a time, so callers can stream any
+// number of them in constant memory.
+type TaskCursor interface {
+	Next(ctx context.Context) bool
+	Task() Task
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// ExportRepository streams tasks.
+type ExportRepository interface {
+	// Export returns a cursor over the tasks matching filter, oldest first.
+	// The cursor lives as long as ctx, not the repository's per-operation
+	// timeout.
+	Export(ctx context.Context, filter TaskFilter) (TaskCursor, error)
+}
+
+// Export validates filter and opens a cursor over the matching tasks. The
+// caller must close it.
+func (s *Service) Export(ctx context.Context, filter TaskFilter) (TaskCursor, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	return s.repo.Export(ctx, filter)
+}
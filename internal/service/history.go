@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+	// MaxActorLength caps the actor stored on revisions; longer ones are
+	// cut.
+	MaxActorLength = 100
+)
+
+type actorKey struct{}
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// FieldChange is the old and new value of a field changed by an update.
+// A nil value means the field was not set.
+type FieldChange struct {
+	Field string `json:"field" bson:"field"`
+	Old   any    `json:"old" bson:"old"`
+	New   any    `json:"new" bson:"new"`
+}
+
+// Revision records one update of a task. Rev is the version the update
+// gave the task, so revisions sort like versions, with gaps where a write
+// changed no field that revisions track. Actor is who made the update,
+// when the request said so, and Task is the task as it was right after
+// the update.
+type Revision struct {
+	TaskID    string        `json:"taskId" bson:"taskId"`
+	Rev       int           `json:"rev" bson:"rev"`
+	Actor     string        `json:"actor,omitempty" bson:"actor,omitempty"`
+	Changes   []FieldChange `json:"changes" bson:"changes"`
+	CreatedAt time.Time     `json:"createdAt" bson:"createdAt"`
+	Task      *Task         `json:"task,omitempty" bson:"task,omitempty"`
+}
+
+// RevisionRepository stores task revisions. Append fails when the task
+// already has a revision with the same number. ListRevisions returns the newest revisions first,
+// starting below before when it is positive, without their task snapshots.
+type RevisionRepository interface {
+	Append(ctx context.Context, revision Revision) (*Revision, error)
+	ListRevisions(ctx context.Context, taskID string, before, limit int) ([]Revision, error)
+	GetRevision(ctx context.Context, taskID string, rev int) (*Revision, error)
+}
+
+func (s *Service) History(ctx context.Context, taskID string, before, limit int) ([]Revision, error) {
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit < 0 || limit > MaxHistoryLimit {
+		return nil, &ValidationError{
+			Field:   "limit",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxHistoryLimit),
+			Value:   limit,
+		}
+	}
+	revisions, err := s.revisions.ListRevisions(ctx, taskID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 && before <= 0 {
+		// Tell unknown tasks apart from tasks that were never updated.
+		if _, err := s.repo.Get(ctx, taskID); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+func (s *Service) Revision(ctx context.Context, taskID string, rev int) (*Revision, error) {
+	if rev < 1 {
+		return nil, &ValidationError{
+			Field:   "rev",
+			Message: "rev must be at least 1",
+			Value:   rev,
+		}
+	}
+	return s.revisions.GetRevision(ctx, taskID, rev)
+}
+
+// WithActor returns a copy of ctx whose updates are recorded as made by
+// actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	actor = strings.TrimSpace(actor)
+	if utf8.RuneCountInString(actor) > MaxActorLength {
+		actor = string([]rune(actor)[:MaxActorLength])
+	}
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// recordRevision stores the fields that differ between before and after.
+// Updates that change nothing leave no revision. The update has landed by
+// then, so a failure is logged rather than failing it: the caller would
+// retry a write that already happened.
+func (s *Service) recordRevision(ctx context.Context, before, after *Task) {
+	changes := diffTasks(before, after)
+	if len(changes) == 0 {
+		return
+	}
+	_, err := s.revisions.Append(context.WithoutCancel(ctx), Revision{
+		TaskID:    after.ID,
+		Rev:       int(after.Version),
+		Actor:     actorFromContext(ctx),
+		Changes:   changes,
+		CreatedAt: s.now().UTC(),
+		Task:      after,
+	})
+	if err != nil {
+		slog.Error("revision not recorded", "err", err, "task_id", after.ID, "version", after.Version)
+	}
+}
+
+// recordRevisions records the revisions of the tasks with the given IDs,
+// updated from their state in before, reading them back in one query. It
+// returns the tasks read back, or none when they could not be read.
+func (s *Service) recordRevisions(ctx context.Context, before []Task, ids []string) []Task {
+	if len(ids) == 0 {
+		return nil
+	}
+	after, err := s.repo.GetMany(context.WithoutCancel(ctx), ids)
+	if err != nil {
+		slog.Error("revisions not recorded", "err", err, "task_ids", ids)
+		return nil
+	}
+	byID := make(map[string]*Task, len(before))
+	for i := range before {
+		byID[before[i].ID] = &before[i]
+	}
+	for i := range after {
+		s.recordRevision(ctx, byID[after[i].ID], &after[i])
+	}
+	return after
+}
+
+// diffTasks compares the fields UpdateTaskRequest can change.
+func diffTasks(before, after *Task) []FieldChange {
+	var changes []FieldChange
+	add := func(field string, old, new any) {
+		changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+	}
+	if before.Title != after.Title {
+		add("title", before.Title, after.Title)
+	}
+	if before.Description != after.Description {
+		add("description", emptyToNil(before.Description), emptyToNil(after.Description))
+	}
+	if before.Done != after.Done {
+		add("done", before.Done, after.Done)
+	}
+	if before.Priority != after.Priority {
+		add("priority", string(before.Priority), string(after.Priority))
+	}
+	if before.ProjectID != after.ProjectID {
+		add("projectId", emptyToNil(before.ProjectID), emptyToNil(after.ProjectID))
+	}
+	if !slices.Equal(before.Tags, after.Tags) {
+		add("tags", tagsValue(before.Tags), tagsValue(after.Tags))
+	}
+	if !equalTime(before.DueAt, after.DueAt) {
+		add("dueAt", timeValue(before.DueAt), timeValue(after.DueAt))
+	}
+	if !equalTime(before.RemindAt, after.RemindAt) {
+		add("remindAt", timeValue(before.RemindAt), timeValue(after.RemindAt))
+	}
+	return changes
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func emptyToNil(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func tagsValue(tags []string) any {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
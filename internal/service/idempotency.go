@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const MaxIdempotencyKeyLength = 255
+
+// ErrIdempotencyKeyReused reports a key that was first used with another
+// request body.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// ErrIdempotencyKeyTakenOver reports a reservation that another request
+// took over, after the one holding it ran past the pending lock timeout.
+var ErrIdempotencyKeyTakenOver = errors.New("idempotency key taken over by another request")
+
+// IdempotencyRecord remembers the outcome of a create request sent with an
+// Idempotency-Key. Task is nil while the first request is still running.
+// Owner identifies the request holding the reservation.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Owner       string
+	Task        *Task
+	CreatedAt   time.Time
+}
+
+// IdempotencyRepository stores idempotency records. Reserve claims key for
+// a new request and reports false, together with the existing record, when
+// another request already claimed it. Complete stores the created task and
+// Release frees the key of a request that failed; both only touch the
+// reservation of owner, which another request may have taken over.
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record IdempotencyRecord) (*IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, key, owner string, task Task) error
+	Release(ctx context.Context, key, owner string) error
+}
+
+// CreateIdempotent creates a task at most once per key. requestHash
+// identifies the request body: a retry with the same key and hash returns
+// the task created by the first request, and replayed reports whether that
+// happened.
+func (s *Service) CreateIdempotent(ctx context.Context, key, requestHash string, req CreateTaskRequest) (task *Task, replayed bool, err error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, false, &ValidationError{
+			Field:   "Idempotency-Key",
+			Message: fmt.Sprintf("Idempotency-Key must be between 1 and %d characters", MaxIdempotencyKeyLength),
+			Value:   key,
+		}
+	}
+
+	owner := newRandomHex(16)
+	existing, reserved, err := s.idempotency.Reserve(ctx, IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		Owner:       owner,
+		CreatedAt:   s.now().UTC(),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !reserved {
+		switch {
+		case existing.RequestHash != requestHash:
+			return nil, false, ErrIdempotencyKeyReused
+		case existing.Task == nil:
+			return nil, false, &ConflictError{Message: "a request with this Idempotency-Key is still in progress"}
+		default:
+			return existing.Task, true, nil
+		}
+	}
+
+	task, err = s.Create(ctx, req)
+	if err != nil {
+		// Failed requests are not remembered, so the client can fix the
+		// request and retry with the same key.
+		if releaseErr := s.idempotency.Release(ctx, key, owner); releaseErr != nil {
+			return nil, false, errors.Join(err, releaseErr)
+		}
+		return nil, false, err
+	}
+	// The task exists now, so the create succeeded whatever happens to the
+	// record. Failing it would make the client retry, and once the pending
+	// key is taken over the retry would create a second task.
+	if err := s.completeIdempotent(context.WithoutCancel(ctx), key, owner, *task); err != nil {
+		slog.Error("idempotency record not completed", "err", err, "key", key, "task_id", task.ID)
+	}
+	return task, false, nil
+}
+
+// completeAttempts bounds how often storing a created task in its
+// idempotency record is tried.
+const completeAttempts = 3
+
+func (s *Service) completeIdempotent(ctx context.Context, key, owner string, task Task) error {
+	for attempt := 1; ; attempt++ {
+		err := s.idempotency.Complete(ctx, key, owner, task)
+		if err == nil || errors.Is(err, ErrIdempotencyKeyTakenOver) || attempt == completeAttempts {
+			return err
+		}
+		timer := time.NewTimer(time.Duration(attempt) * 100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
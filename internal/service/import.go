@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const MaxImportRows = 10000
+
+// ImportTask is one row of an import. CreatedAt and CompletedAt, when set,
+// keep the times of a task exported earlier; otherwise they are set as
+// Create sets them.
+type ImportTask struct {
+	CreateTaskRequest
+	CreatedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+// ImportResult is the outcome of one row. Task is the created task, or on a
+// dry run the task that would be created, and is nil when Err is set.
+type ImportResult struct {
+	Task *Task
+	Err  error
+}
+
+// Import validates every row like Create does and inserts the valid ones
+// in batches of MaxBatchOperations; invalid rows do not stop the others.
+// A row whose UID already belongs to a task fails with a ConflictError, so
+// importing the same calendar twice does not duplicate its tasks. A dry
+// run only validates. If a batch fails as a whole, the batches before it
+// stay inserted.
+func (s *Service) Import(ctx context.Context, rows []ImportTask, dryRun bool) ([]ImportResult, error) {
+	if len(rows) == 0 || len(rows) > MaxImportRows {
+		return nil, &ValidationError{
+			Field:   "rows",
+			Message: fmt.Sprintf("an import must contain between 1 and %d rows", MaxImportRows),
+			Value:   len(rows),
+		}
+	}
+
+	uids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		uids = append(uids, row.UID)
+	}
+	taken, err := s.takenUIDs(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ImportResult, len(rows))
+	writes := make([]BatchWrite, 0, len(rows))
+	writeRows := make([]int, 0, len(rows))
+	projects := map[string]error{}
+	for i, row := range rows {
+		if taken[row.UID] {
+			results[i].Err = &ConflictError{Message: fmt.Sprintf("a task with UID %q already exists", row.UID)}
+			continue
+		}
+		task, err := s.importTask(ctx, row, projects)
+		if err != nil {
+			var vErr *ValidationError
+			if !errors.As(err, &vErr) {
+				return nil, err
+			}
+			results[i].Err = err
+			continue
+		}
+		if row.UID != "" {
+			taken[row.UID] = true
+		}
+		results[i].Task = task
+		writes = append(writes, BatchWrite{Kind: BatchCreate, Task: *task})
+		writeRows = append(writeRows, i)
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	for start := 0; start < len(writes); start += MaxBatchOperations {
+		batch := writes[start:min(start+MaxBatchOperations, len(writes))]
+		writeErrs, err := s.repo.ApplyBatch(ctx, batch, false)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(batch))
+		for j, write := range batch {
+			if writeErrs[j] == nil {
+				ids = append(ids, write.ID)
+			}
+		}
+		created := map[string]*Task{}
+		if len(ids) > 0 {
+			tasks, err := s.repo.GetMany(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			for i := range tasks {
+				created[tasks[i].ID] = &tasks[i]
+			}
+		}
+
+		var events []Event
+		for j, write := range batch {
+			result := &results[writeRows[start+j]]
+			result.Task, result.Err = created[write.ID], writeErrs[j]
+			if result.Err != nil {
+				result.Task = nil
+				continue
+			}
+			events = append(events, s.newEvent(EventTaskCreated, write.ID, result.Task))
+		}
+		s.publish(ctx, events...)
+	}
+	return results, nil
+}
+
+// importTask builds the task of row. projects caches the outcome of the
+// project check, since imported rows tend to share a few projects.
+func (s *Service) importTask(ctx context.Context, row ImportTask, projects map[string]error) (*Task, error) {
+	task, err := s.buildTask(row.CreateTaskRequest)
+	if err != nil {
+		return nil, err
+	}
+	if row.ProjectID != "" {
+		err, ok := projects[row.ProjectID]
+		if !ok {
+			err = s.ensureProject(ctx, row.ProjectID)
+			projects[row.ProjectID] = err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if row.CreatedAt != nil {
+		task.CreatedAt = row.CreatedAt.UTC()
+	}
+	switch {
+	case !task.Done && row.CompletedAt != nil:
+		return nil, &ValidationError{Field: "completedAt", Message: "completedAt requires done to be true", Value: *row.CompletedAt}
+	case !task.Done:
+		task.CompletedAt = nil
+	case row.CompletedAt != nil:
+		completedAt := row.CompletedAt.UTC()
+		if completedAt.Before(task.CreatedAt) {
+			return nil, &ValidationError{Field: "completedAt", Message: "completedAt must not be before createdAt", Value: *row.CompletedAt}
+		}
+		task.CompletedAt = &completedAt
+	default:
+		completedAt := task.CreatedAt
+		task.CompletedAt = &completedAt
+	}
+	return task, nil
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MaxPatchOperations = 100
+
+// PatchOperation is one RFC 6902 JSON Patch operation on a task. Paths
+// address the fields of UpdateTaskRequest and single tags as /tags/{index}
+// or /tags/- to append; test may also check /version. move and copy are not
+// supported.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchTest    = "test"
+)
+
+// PatchTagsAppend is the index of /tags/- in a normalized operation.
+const PatchTagsAppend = -1
+
+// patchFields lists the paths that can be patched; required fields can be
+// replaced but not removed.
+var patchFields = map[string]struct{ required bool }{
+	"title":       {required: true},
+	"description": {},
+	"done":        {required: true},
+	"priority":    {required: true},
+	"projectId":   {},
+	"tags":        {},
+	"dueAt":       {},
+	"remindAt":    {},
+}
+
+// Patch applies a JSON Patch to a task. The operations are checked in
+// order against the current task and the result goes through the same
+// validation as Update; the repository then applies them as a single
+// atomic update guarded by the version they were checked against.
+func (s *Service) Patch(ctx context.Context, id string, ops []PatchOperation, ifVersion *int64) (*Task, error) {
+	if len(ops) == 0 || len(ops) > MaxPatchOperations {
+		return nil, &ValidationError{
+			Field:   "body",
+			Message: fmt.Sprintf("patch must contain between 1 and %d operations", MaxPatchOperations),
+			Value:   len(ops),
+		}
+	}
+	return s.applyPatch(ctx, id, ifVersion, func(*Task) []PatchOperation {
+		return ops
+	}, func(index int, _ PatchOperation) string {
+		return fmt.Sprintf("body[%d]", index)
+	})
+}
+
+// MergePatch applies an RFC 7396 merge patch: every member replaces the
+// field of the same name and null removes it. Removing a field that is not
+// set is a no-op.
+func (s *Service) MergePatch(ctx context.Context, id string, patch map[string]any, ifVersion *int64) (*Task, error) {
+	if len(patch) == 0 {
+		return nil, &ValidationError{
+			Field:   "body",
+			Message: "at least one field must be provided",
+		}
+	}
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		if _, ok := patchFields[field]; !ok {
+			return nil, &ValidationError{Field: field, Message: "unknown field"}
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return s.applyPatch(ctx, id, ifVersion, func(current *Task) []PatchOperation {
+		ops := make([]PatchOperation, 0, len(fields))
+		for _, field := range fields {
+			path := "/" + field
+			switch {
+			case patch[field] != nil:
+				ops = append(ops, PatchOperation{Op: PatchAdd, Path: path, Value: patch[field]})
+			case hasField(current, field) || patchFields[field].required:
+				ops = append(ops, PatchOperation{Op: PatchRemove, Path: path})
+			}
+		}
+		return ops
+	}, func(_ int, op PatchOperation) string {
+		return strings.TrimPrefix(op.Path, "/")
+	})
+}
+
+// patchAttempts bounds how often a patch without If-Match is re-evaluated
+// when the task changes between reading and writing it.
+const patchAttempts = 3
+
+func (s *Service) applyPatch(ctx context.Context, id string, ifVersion *int64, build func(current *Task) []PatchOperation, locate func(index int, op PatchOperation) string) (*Task, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ifVersion != nil && *ifVersion != current.Version {
+			return nil, &VersionMismatchError{Current: current.Version}
+		}
+		ops, err := s.checkPatch(ctx, current, build(current), locate)
+		if err != nil {
+			return nil, err
+		}
+		if len(ops) == 0 {
+			return current, nil
+		}
+
+		task, err := s.repo.Patch(ctx, id, ops, current.Version)
+		var mismatch *VersionMismatchError
+		if errors.As(err, &mismatch) && ifVersion == nil && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.recordRevision(ctx, current, task)
+		return s.publishTask(ctx, EventTaskUpdated, task, nil)
+	}
+}
+
+// checkPatch applies ops to a copy of current and validates the result. It
+// returns the operations that write, with their values normalized, or none
+// when the patch only tests.
+func (s *Service) checkPatch(ctx context.Context, current *Task, ops []PatchOperation, locate func(index int, op PatchOperation) string) ([]PatchOperation, error) {
+	candidate := *current
+	candidate.Tags = slices.Clone(current.Tags)
+	writes := make([]PatchOperation, 0, len(ops))
+	for i, op := range ops {
+		op, err := applyPatchOperation(&candidate, op)
+		if err != nil {
+			var vErr *ValidationError
+			if errors.As(err, &vErr) {
+				return nil, &ValidationError{
+					Field:   locate(i, op),
+					Message: fmt.Sprintf("%s %s: %s", op.Op, op.Path, vErr.Message),
+					Value:   vErr.Value,
+				}
+			}
+			return nil, err
+		}
+		if op.Op != PatchTest {
+			writes = append(writes, op)
+		}
+	}
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	for i, tag := range candidate.Tags {
+		if slices.Contains(candidate.Tags[:i], tag) {
+			return nil, &ValidationError{Field: "tags", Message: "duplicate tag", Value: tag}
+		}
+	}
+	if err := validateDescription(candidate.Description); err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(candidate.DueAt, candidate.RemindAt); err != nil {
+		return nil, err
+	}
+	if candidate.ProjectID != current.ProjectID {
+		if err := s.ensureProject(ctx, candidate.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	if candidate.Done && !current.Done {
+		if err := s.ensureUnblocked(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+	return writes, nil
+}
+
+// applyPatchOperation applies op to task and returns it with its path
+// checked and its value converted to the field's type: string, bool,
+// Priority, []string or time.Time, or int64 for /version.
+func applyPatchOperation(task *Task, op PatchOperation) (PatchOperation, error) {
+	field, index, err := parsePatchPath(op.Path)
+	if err != nil {
+		return op, err
+	}
+	switch op.Op {
+	case PatchAdd, PatchRemove, PatchReplace, PatchTest:
+	case "move", "copy":
+		return op, &ValidationError{Field: "op", Message: "move and copy are not supported", Value: op.Op}
+	default:
+		return op, &ValidationError{Field: "op", Message: "op must be one of add, remove, replace, test", Value: op.Op}
+	}
+	if field == "version" {
+		if op.Op != PatchTest || index != nil {
+			return op, &ValidationError{Field: "path", Message: "version can only be tested", Value: op.Path}
+		}
+		version, err := patchInt(op.Value)
+		if err != nil {
+			return op, err
+		}
+		op.Value = version
+		if version != task.Version {
+			return op, &ConflictError{Message: fmt.Sprintf("test %s failed", op.Path)}
+		}
+		return op, nil
+	}
+	if index != nil {
+		return applyTagOperation(task, op, *index)
+	}
+
+	if op.Op == PatchRemove {
+		if patchFields[field].required {
+			return op, &ValidationError{Field: "path", Message: field + " cannot be removed", Value: op.Path}
+		}
+		if !hasField(task, field) {
+			return op, &ValidationError{Field: "path", Message: "path does not exist", Value: op.Path}
+		}
+		op.Value = nil
+		clearField(task, field)
+		return op, nil
+	}
+	if op.Op == PatchReplace && !hasField(task, field) {
+		return op, &ValidationError{Field: "path", Message: "path does not exist", Value: op.Path}
+	}
+	value, err := patchValue(field, op.Value)
+	if err != nil {
+		return op, err
+	}
+	op.Value = value
+	if op.Op == PatchTest {
+		if !fieldEquals(task, field, value) {
+			return op, &ConflictError{Message: fmt.Sprintf("test %s failed", op.Path)}
+		}
+		return op, nil
+	}
+	setField(task, field, value)
+	return op, nil
+}
+
+func applyTagOperation(task *Task, op PatchOperation, index int) (PatchOperation, error) {
+	size := len(task.Tags)
+	switch {
+	case index == PatchTagsAppend && op.Op != PatchAdd:
+		return op, &ValidationError{Field: "path", Message: "/tags/- can only be used with add", Value: op.Path}
+	case op.Op == PatchAdd && index > size, op.Op != PatchAdd && index >= size:
+		return op, &ValidationError{Field: "path", Message: "tag index out of range", Value: op.Path}
+	}
+	if index == PatchTagsAppend {
+		index = size
+	}
+
+	if op.Op == PatchRemove {
+		op.Value = nil
+		task.Tags = slices.Delete(task.Tags, index, index+1)
+		return op, nil
+	}
+	tag, ok := op.Value.(string)
+	if !ok {
+		return op, &ValidationError{Field: "value", Message: "tag must be a string", Value: op.Value}
+	}
+	tag, err := normalizeTag("value", tag)
+	if err != nil {
+		return op, err
+	}
+	op.Value = tag
+	switch op.Op {
+	case PatchAdd:
+		task.Tags = slices.Insert(task.Tags, index, tag)
+	case PatchReplace:
+		task.Tags[index] = tag
+	case PatchTest:
+		if task.Tags[index] != tag {
+			return op, &ConflictError{Message: fmt.Sprintf("test %s failed", op.Path)}
+		}
+	}
+	return op, nil
+}
+
+// ParsePatchPath splits a patch path into the field and, for /tags/{index},
+// the tag index, which is PatchTagsAppend for /tags/-.
+func ParsePatchPath(path string) (string, *int) {
+	field, index, _ := parsePatchPath(path)
+	return field, index
+}
+
+func parsePatchPath(path string) (string, *int, error) {
+	invalid := &ValidationError{Field: "path", Message: "unsupported path", Value: path}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if !strings.HasPrefix(path, "/") || len(parts) > 2 {
+		return "", nil, invalid
+	}
+	field := parts[0]
+	if _, ok := patchFields[field]; !ok && field != "version" {
+		return "", nil, invalid
+	}
+	if len(parts) == 1 {
+		return field, nil, nil
+	}
+	if field != "tags" {
+		return "", nil, invalid
+	}
+	if parts[1] == "-" {
+		index := PatchTagsAppend
+		return field, &index, nil
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 || (len(parts[1]) > 1 && parts[1][0] == '0') {
+		return "", nil, invalid
+	}
+	return field, &index, nil
+}
+
+// patchValue converts a decoded JSON value to the type of field.
+func patchValue(field string, value any) (any, error) {
+	invalid := func(message string) error {
+		return &ValidationError{Field: "value", Message: message, Value: value}
+	}
+	switch field {
+	case "title":
+		title, ok := value.(string)
+		if !ok || len(strings.TrimSpace(title)) < 3 {
+			return nil, invalid("title must be at least 3 characters")
+		}
+		return strings.TrimSpace(title), nil
+	case "description", "projectId":
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid(field + " must be a string")
+		}
+		return text, nil
+	case "done":
+		done, ok := value.(bool)
+		if !ok {
+			return nil, invalid("done must be a boolean")
+		}
+		return done, nil
+	case "priority":
+		text, _ := value.(string)
+		priority := Priority(text)
+		if err := validatePriority("value", priority); err != nil {
+			return nil, err
+		}
+		return priority, nil
+	case "tags":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, invalid("tags must be an array of strings")
+		}
+		tags := make([]string, 0, len(items))
+		for _, item := range items {
+			tag, ok := item.(string)
+			if !ok {
+				return nil, invalid("tags must be an array of strings")
+			}
+			tags = append(tags, tag)
+		}
+		return normalizeTags(tags)
+	case "dueAt", "remindAt":
+		text, _ := value.(string)
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, invalid(field + " must be an RFC 3339 date-time")
+		}
+		return t.UTC(), nil
+	}
+	return nil, invalid("unsupported field")
+}
+
+func patchInt(value any) (int64, error) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, &ValidationError{Field: "value", Message: "version must be an integer", Value: value}
+	}
+	return int64(number), nil
+}
+
+func hasField(task *Task, field string) bool {
+	switch field {
+	case "description":
+		return task.Description != ""
+	case "projectId":
+		return task.ProjectID != ""
+	case "tags":
+		return task.Tags != nil
+	case "dueAt":
+		return task.DueAt != nil
+	case "remindAt":
+		return task.RemindAt != nil
+	}
+	return true
+}
+
+func clearField(task *Task, field string) {
+	switch field {
+	case "description":
+		task.Description = ""
+	case "projectId":
+		task.ProjectID = ""
+	case "tags":
+		task.Tags = nil
+	case "dueAt":
+		task.DueAt = nil
+	case "remindAt":
+		task.RemindAt = nil
+	}
+}
+
+func setField(task *Task, field string, value any) {
+	switch field {
+	case "title":
+		task.Title = value.(string)
+	case "description":
+		task.Description = value.(string)
+	case "done":
+		task.Done = value.(bool)
+	case "priority":
+		task.Priority = value.(Priority)
+	case "projectId":
+		task.ProjectID = value.(string)
+	case "tags":
+		task.Tags = value.([]string)
+	case "dueAt":
+		t := value.(time.Time)
+		task.DueAt = &t
+	case "remindAt":
+		t := value.(time.Time)
+		task.RemindAt = &t
+	}
+}
+
+func fieldEquals(task *Task, field string, value any) bool {
+	switch field {
+	case "title":
+		return task.Title == value
+	case "description":
+		return task.Description == value
+	case "done":
+		return task.Done == value
+	case "priority":
+		return task.Priority == value
+	case "projectId":
+		return task.ProjectID == value
+	case "tags":
+		return slices.Equal(task.Tags, value.([]string))
+	case "dueAt":
+		return task.DueAt != nil && task.DueAt.Equal(value.(time.Time))
+	case "remindAt":
+		return task.RemindAt != nil && task.RemindAt.Equal(value.(time.Time))
+	}
+	return false
+}
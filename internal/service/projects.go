@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	MaxProjectNameLength = 100
+)
+
+var (
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrInvalidProjectID = errors.New("invalid project id")
+)
+
+// DeleteMode chooses what happens to the tasks of a deleted project.
+type DeleteMode string
+
+const (
+	// DeleteRestrict refuses to delete a project that still has tasks.
+	DeleteRestrict DeleteMode = "restrict"
+	// DeleteCascade moves the project's tasks to the trash along with it.
+	DeleteCascade DeleteMode = "cascade"
+)
+
+type Project struct {
+	ID          string    `json:"id" bson:"_id,omitempty"`
+	Name        string    `json:"name" bson:"name"`
+	Description string    `json:"description,omitempty" bson:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type CreateProjectRequest struct {
+	Name        string
+	Description string
+}
+
+type UpdateProjectRequest struct {
+	Name        *string
+	Description *string
+}
+
+type ProjectRepository interface {
+	Create(ctx context.Context, project Project) (*Project, error)
+	Get(ctx context.Context, id string) (*Project, error)
+	List(ctx context.Context) ([]Project, error)
+	Update(ctx context.Context, id string, update UpdateProjectRequest) (*Project, error)
+	Delete(ctx context.Context, id string) error
+}
+
+func (s *Service) CreateProject(ctx context.Context, req CreateProjectRequest) (*Project, error) {
+	name, err := validateProjectName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.projects.Create(ctx, Project{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		CreatedAt:   s.now().UTC(),
+	})
+}
+
+func (s *Service) GetProject(ctx context.Context, id string) (*Project, error) {
+	return s.projects.Get(ctx, id)
+}
+
+func (s *Service) ListProjects(ctx context.Context) ([]Project, error) {
+	return s.projects.List(ctx)
+}
+
+func (s *Service) UpdateProject(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error) {
+	if req.Name == nil && req.Description == nil {
+		return nil, &ValidationError{
+			Field:   "body",
+			Message: "at least one field must be provided",
+		}
+	}
+	if req.Name != nil {
+		name, err := validateProjectName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		req.Name = &name
+	}
+	if req.Description != nil {
+		trimmed := strings.TrimSpace(*req.Description)
+		req.Description = &trimmed
+	}
+	return s.projects.Update(ctx, id, req)
+}
+
+// DeleteProject removes a project. With DeleteRestrict it fails with a
+// conflict while the project has live tasks; with DeleteCascade its tasks
+// are moved to the trash and their count is returned.
+func (s *Service) DeleteProject(ctx context.Context, id string, mode DeleteMode) (int64, error) {
+	if _, err := s.projects.Get(ctx, id); err != nil {
+		return 0, err
+	}
+
+	filter := TaskFilter{ProjectID: id}
+	var deleted int64
+	switch mode {
+	case "", DeleteRestrict:
+		count, err := s.repo.Count(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			return 0, &ConflictError{Message: fmt.Sprintf("project still has %d task(s)", count)}
+		}
+	case DeleteCascade:
+		result, err := s.deleteWhere(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		deleted = result.Modified
+	default:
+		return 0, &ValidationError{
+			Field:   "mode",
+			Message: "mode must be restrict or cascade",
+			Value:   mode,
+		}
+	}
+
+	if err := s.projects.Delete(ctx, id); err != nil {
+		return 0, err
+	}
+
+	// Tasks created or moved into the project while it was being deleted
+	// escaped the pass above, so a second pass after the delete catches
+	// them: cascade trashes them too, restrict leaves them without a
+	// project, like a restore does.
+	switch mode {
+	case DeleteCascade:
+		result, err := s.deleteWhere(ctx, filter)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.Modified
+	default:
+		noProject := ""
+		detach := UpdateTaskRequest{ProjectID: &noProject}
+		if _, err := s.rewriteWhere(ctx, filter, func(Task) (BatchWrite, bool) {
+			return BatchWrite{Kind: BatchUpdate, Update: detach}, true
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return deleted, nil
+}
+
+// ListProjectTasks lists the tasks of an existing project.
+func (s *Service) ListProjectTasks(ctx context.Context, id string, filter TaskFilter, opts ListOptions) (*TaskPage, error) {
+	if _, err := s.projects.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	filter.ProjectID = id
+	return s.List(ctx, filter, opts)
+}
+
+// ensureProject checks that a task is being assigned to an existing project.
+func (s *Service) ensureProject(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	if _, err := s.projects.Get(ctx, id); err != nil {
+		if errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrInvalidProjectID) {
+			return &ValidationError{Field: "projectId", Message: "project not found", Value: id}
+		}
+		return err
+	}
+	return nil
+}
+
+func validateProjectName(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" || len(trimmed) > MaxProjectNameLength {
+		return "", &ValidationError{
+			Field:   "name",
+			Message: fmt.Sprintf("name must be between 1 and %d characters", MaxProjectNameLength),
+			Value:   name,
+		}
+	}
+	return trimmed, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OpenAgeBounds split open tasks by how long ago they were created; the
+// last bucket holds everything older than the last bound.
+var OpenAgeBounds = []time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+}
+
+type TaskStats struct {
+	Total   int64       `json:"total"`
+	Done    int64       `json:"done"`
+	Open    int64       `json:"open"`
+	Tags    []TagStats  `json:"tags" doc:"Per-tag counts, most used first"`
+	OpenAge []AgeBucket `json:"openAge" doc:"Open tasks by age, youngest first"`
+}
+
+type TagStats struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Total int64  `json:"total" bson:"total"`
+	Done  int64  `json:"done" bson:"done"`
+	Open  int64  `json:"open" bson:"open"`
+}
+
+// AgeBucket counts the open tasks created between MinDays and MaxDays ago;
+// the oldest bucket has no MaxDays.
+type AgeBucket struct {
+	Label   string `json:"label" example:"7-30d"`
+	MinDays int    `json:"minDays"`
+	MaxDays *int   `json:"maxDays,omitempty"`
+	Count   int64  `json:"count"`
+}
+
+// StatsRepository aggregates task statistics in the database.
+type StatsRepository interface {
+	// Stats computes the statistics of the tasks matching filter. OpenAge
+	// has one count per bucket of OpenAgeBounds, ages measured from now.
+	Stats(ctx context.Context, filter TaskFilter, now time.Time) (*TaskStats, error)
+}
+
+// Stats returns totals, per-tag counts and the age distribution of open
+// tasks among the tasks matching filter.
+func (s *Service) Stats(ctx context.Context, filter TaskFilter) (*TaskStats, error) {
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	stats, err := s.repo.Stats(ctx, filter, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	labelAgeBuckets(stats.OpenAge)
+	return stats, nil
+}
+
+func labelAgeBuckets(buckets []AgeBucket) {
+	minDays := 0
+	for i := range buckets {
+		buckets[i].MinDays = minDays
+		if i == len(OpenAgeBounds) {
+			buckets[i].Label = fmt.Sprintf("%dd+", minDays)
+			continue
+		}
+		maxDays := int(OpenAgeBounds[i] / (24 * time.Hour))
+		buckets[i].MaxDays = &maxDays
+		buckets[i].Label = fmt.Sprintf("%d-%dd", minDays, maxDays)
+		minDays = maxDays
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxTagLength = 50
+	// MaxMergeTags caps how many tags one merge can fold into another.
+	MaxMergeTags = 50
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+
+// TagCount is a tag with the number of tasks, outside the trash, using it.
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+	Open  int64  `json:"open" bson:"open" doc:"Tasks with this tag that are not done"`
+}
+
+// TagChange reports a rename or merge.
+type TagChange struct {
+	Tag      string `json:"tag"`
+	Modified int64  `json:"modified" doc:"Number of tasks changed"`
+}
+
+// TagReplacement is a task ReplaceTags changed. Tags are the tags it had
+// before, nil when it got one of the sources while the replacement ran.
+type TagReplacement struct {
+	ID   string
+	Tags []string
+}
+
+// TagRepository reads and rewrites tags across tasks.
+type TagRepository interface {
+	// ListTags returns every tag in use, most used first.
+	ListTags(ctx context.Context) ([]TagCount, error)
+	// ReplaceTags replaces each of sources with target on every task
+	// matching filter, without leaving duplicates, and returns the tasks
+	// it changed.
+	ReplaceTags(ctx context.Context, filter TaskFilter, sources []string, target string) ([]TagReplacement, error)
+}
+
+// ListTags returns the tags in use with their usage counts.
+func (s *Service) ListTags(ctx context.Context) ([]TagCount, error) {
+	return s.repo.ListTags(ctx)
+}
+
+// RenameTag renames tag to name on every task. Renaming to a tag that is
+// already in use merges the two.
+func (s *Service) RenameTag(ctx context.Context, tag, name string) (*TagChange, error) {
+	name, err := normalizeTag("name", name)
+	if err != nil {
+		return nil, err
+	}
+	return s.MergeTags(ctx, []string{tag}, name)
+}
+
+// MergeTags replaces every tag in sources with target on every task. It
+// fails with ErrTagNotFound when no task uses any of the sources.
+func (s *Service) MergeTags(ctx context.Context, sources []string, target string) (*TagChange, error) {
+	if len(sources) == 0 || len(sources) > MaxMergeTags {
+		return nil, &ValidationError{
+			Field:   "tags",
+			Message: fmt.Sprintf("between 1 and %d tags must be provided", MaxMergeTags),
+			Value:   len(sources),
+		}
+	}
+	target, err := normalizeTag("into", target)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := normalizeTags(sources)
+	if err != nil {
+		return nil, err
+	}
+	from := make([]string, 0, len(normalized))
+	for _, tag := range normalized {
+		if tag != target {
+			from = append(from, tag)
+		}
+	}
+	// Stored tags may predate normalization, so the sources are matched as
+	// given too.
+	for _, tag := range sources {
+		if tag != target && !slices.Contains(from, tag) {
+			from = append(from, tag)
+		}
+	}
+	if len(from) == 0 {
+		return nil, &ValidationError{Field: "into", Message: "tags are already named " + target, Value: target}
+	}
+
+	// Each live task changed gets a revision and a task.updated event;
+	// tasks in the trash are rewritten without either.
+	live, err := s.repo.ReplaceTags(ctx, TaskFilter{}, from, target)
+	if err != nil {
+		return nil, err
+	}
+	s.recordTagChanges(ctx, live)
+	trashed, err := s.repo.ReplaceTags(ctx, TaskFilter{Trashed: true}, from, target)
+	if err != nil {
+		return nil, err
+	}
+	modified := int64(len(live) + len(trashed))
+	if modified == 0 {
+		return nil, ErrTagNotFound
+	}
+	return &TagChange{Tag: target, Modified: modified}, nil
+}
+
+// recordTagChanges records the revisions of the tasks ReplaceTags changed
+// and publishes their events, reading them back a page at a time. Like
+// publish it only logs failures: the tags have been replaced by then.
+func (s *Service) recordTagChanges(ctx context.Context, changed []TagReplacement) {
+	for start := 0; start < len(changed); start += bulkPageSize {
+		page := changed[start:min(start+bulkPageSize, len(changed))]
+		ids := make([]string, len(page))
+		previous := make(map[string][]string, len(page))
+		for i, task := range page {
+			ids[i] = task.ID
+			if task.Tags != nil {
+				previous[task.ID] = task.Tags
+			}
+		}
+		after, err := s.repo.GetMany(context.WithoutCancel(ctx), ids)
+		if err != nil {
+			slog.Error("tag changes not recorded", "err", err, "task_ids", ids)
+			continue
+		}
+		events := make([]Event, 0, len(after))
+		for i := range after {
+			if tags, ok := previous[after[i].ID]; ok {
+				before := after[i]
+				before.Tags = tags
+				s.recordRevision(ctx, &before, &after[i])
+			}
+			events = append(events, s.newEvent(EventTaskUpdated, after[i].ID, &after[i]))
+		}
+		s.publish(ctx, events...)
+	}
+}
+
+// normalizeTags trims and lowercases tags and drops duplicates, keeping
+// the first occurrence.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag("tags", tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func normalizeTag(field, tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(tag))
+	if normalized == "" {
+		return "", &ValidationError{Field: field, Message: "tags must not be blank", Value: tag}
+	}
+	if utf8.RuneCountInString(normalized) > MaxTagLength {
+		return "", &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("tags must be at most %d characters", MaxTagLength),
+			Value:   tag,
+		}
+	}
+	return normalized, nil
+}
@@ -3,43 +3,148 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
 
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists every priority in ascending order of urgency.
+var Priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+func (p Priority) Valid() bool {
+	return p.Rank() >= 0
+}
+
+// Rank is the position of p in Priorities, or -1 if p is unknown. Ordering
+// by priority uses the rank rather than the name.
+func (p Priority) Rank() int {
+	for i, candidate := range Priorities {
+		if candidate == p {
+			return i
+		}
+	}
+	return -1
+}
+
 // Task is the domain model returned by the API.
 type Task struct {
-	ID        string    `json:"id" bson:"_id,omitempty"`
-	Title     string    `json:"title" bson:"title"`
-	Done      bool      `json:"done" bson:"done"`
-	Tags      []string  `json:"tags,omitempty" bson:"tags,omitempty"`
-	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
-	Internal  string    `json:"-" bson:"-"`
+	ID                string            `json:"id" bson:"_id,omitempty"`
+	Title             string            `json:"title" bson:"title"`
+	Description       string            `json:"description,omitempty" bson:"description,omitempty"`
+	DescriptionHTML   string            `json:"descriptionHtml,omitempty" bson:"-" doc:"Sanitized HTML rendering of description, only set when requested"`
+	Done              bool              `json:"done" bson:"done"`
+	Priority          Priority          `json:"priority" bson:"priority"`
+	ProjectID         string            `json:"projectId,omitempty" bson:"projectId,omitempty"`
+	Tags              []string          `json:"tags,omitempty" bson:"tags,omitempty"`
+	BlockedBy         []string          `json:"blockedBy,omitempty" bson:"blockedBy,omitempty"`
+	Checklist         []ChecklistItem   `json:"checklist,omitempty" bson:"checklist,omitempty"`
+	ChecklistProgress ChecklistProgress `json:"checklistProgress" bson:"-"`
+	CreatedAt         time.Time         `json:"createdAt" bson:"createdAt"`
+	CompletedAt       *time.Time        `json:"completedAt,omitempty" bson:"completedAt,omitempty" doc:"When the task was last marked done"`
+	DueAt             *time.Time        `json:"dueAt,omitempty" bson:"dueAt,omitempty"`
+	RemindAt          *time.Time        `json:"remindAt,omitempty" bson:"remindAt,omitempty"`
+	UID               string            `json:"uid,omitempty" bson:"uid,omitempty" doc:"iCalendar UID the task was imported with"`
+	DeletedAt         *time.Time        `json:"deletedAt,omitempty" bson:"deletedAt,omitempty" doc:"Set while the task is in the trash"`
+	Version           int64             `json:"version" bson:"version" doc:"Incremented on every change, also sent as the ETag"`
+	Score             float64           `json:"score,omitempty" bson:"-" doc:"Relevance score, only set on search results"`
+	Internal          string            `json:"-" bson:"-"`
 	// internalNote is unexported, so json/bson ignore it even with tags.
 	internalNote string `json:"internalNote" bson:"internalNote"`
 }
 
 type CreateTaskRequest struct {
-	Title string
-	Done  *bool
-	Tags  []string
+	Title       string
+	Description string
+	Done        *bool
+	Priority    Priority
+	ProjectID   string
+	Tags        []string
+	DueAt       *time.Time
+	RemindAt    *time.Time
+	// UID is the iCalendar UID of a task that comes from a calendar. It
+	// must not belong to another task.
+	UID string
 }
 
 type UpdateTaskRequest struct {
-	Title *string
-	Done  *bool
-	Tags  *[]string
+	Title       *string
+	Description *string
+	Done        *bool
+	Priority    *Priority
+	// ProjectID moves the task to another project; an empty string removes
+	// it from its project.
+	ProjectID *string
+	Tags      *[]string
+	DueAt     *time.Time
+	RemindAt  *time.Time
+	// IfVersion makes the update fail with a VersionMismatchError unless the
+	// task is still at this version.
+	IfVersion *int64
 }
 
+// TaskFilter selects tasks. Query is a full-text search over titles and
+// tags; results that match it carry a relevance Score.
+// DueBefore and DueAfter bound dueAt exclusively, CreatedBefore and
+// CreatedAfter bound createdAt the same way. Overdue selects open
+// tasks whose dueAt has passed (or, when false, every other task). Blocked
+// selects tasks with at least one open blocker. Trashed switches the filter
+// from live tasks to tasks in the trash.
 type TaskFilter struct {
-	Done *bool
-	Tag  string
+	ProjectID     string
+	Done          *bool
+	Priorities    []Priority
+	Tag           string
+	Query         string
+	DueBefore     *time.Time
+	DueAfter      *time.Time
+	CreatedBefore *time.Time
+	CreatedAfter  *time.Time
+	Overdue       *bool
+	Blocked       *bool
+	Trashed       bool
+}
+
+const (
+	MaxQueryLength       = 200
+	MaxDescriptionLength = 20000
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// ListOptions controls ordering and paging of list results. Sort is a
+// comma-separated list of field names, each optionally prefixed with "-" for
+// descending order. Cursor is the opaque value returned as NextCursor by a
+// previous page and is only valid for the same Sort.
+type ListOptions struct {
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+type TaskPage struct {
+	Items      []Task
+	NextCursor string
 }
 
 var (
 	ErrNotFound   = errors.New("task not found")
 	ErrInvalidID  = errors.New("invalid task id")
 	ErrValidation = errors.New("validation failed")
+	ErrConflict   = errors.New("conflict")
+	// ErrPreconditionFailed is wrapped by VersionMismatchError.
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
 type ValidationError struct {
@@ -56,25 +161,114 @@ func (e *ValidationError) Unwrap() error {
 	return ErrValidation
 }
 
+// ConflictError reports a request that is valid on its own but conflicts
+// with the current state of the data.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// VersionMismatchError reports a write that expected another version of the
+// task than the current one.
+type VersionMismatchError struct {
+	Current int64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("task was modified, current version is %d", e.Current)
+}
+
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrPreconditionFailed
+}
+
 type TaskRepository interface {
 	Create(ctx context.Context, task Task) (*Task, error)
 	Get(ctx context.Context, id string) (*Task, error)
-	List(ctx context.Context, filter TaskFilter) ([]Task, error)
+	List(ctx context.Context, filter TaskFilter, opts ListOptions) (*TaskPage, error)
 	Update(ctx context.Context, id string, update UpdateTaskRequest) (*Task, error)
-	Delete(ctx context.Context, id string) error
+	// Patch applies the normalized write operations of a JSON Patch to the
+	// task at version, atomically.
+	Patch(ctx context.Context, id string, ops []PatchOperation, version int64) (*Task, error)
+	// Delete moves a task to the trash; Purge removes a trashed task for good.
+	// A non-nil ifVersion must match the current version of the task.
+	Delete(ctx context.Context, id string, ifVersion *int64) error
+	Restore(ctx context.Context, id string) (*Task, error)
+	Purge(ctx context.Context, id string) error
+	// EmptyTrash purges every task in the trash and returns their IDs.
+	EmptyTrash(ctx context.Context) ([]string, error)
+	Count(ctx context.Context, filter TaskFilter) (int64, error)
 	Ping(ctx context.Context) error
+	ChecklistRepository
+	DependencyRepository
+	BatchRepository
+	TagRepository
+	StatsRepository
+	TimeseriesRepository
+	ExportRepository
+	CalendarRepository
 }
 
 type Service struct {
-	repo TaskRepository
-	now  func() time.Time
+	repo        TaskRepository
+	projects    ProjectRepository
+	revisions   RevisionRepository
+	idempotency IdempotencyRepository
+	webhooks    WebhookRepository
+	now         func() time.Time
 }
 
-func New(repo TaskRepository) *Service {
-	return &Service{repo: repo, now: time.Now}
+func New(repo TaskRepository, projects ProjectRepository, revisions RevisionRepository, idempotency IdempotencyRepository, webhooks WebhookRepository) *Service {
+	return &Service{
+		repo:        repo,
+		projects:    projects,
+		revisions:   revisions,
+		idempotency: idempotency,
+		webhooks:    webhooks,
+		now:         time.Now,
+	}
 }
 
 func (s *Service) Create(ctx context.Context, req CreateTaskRequest) (*Task, error) {
+	task, err := s.newTask(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.repo.Create(ctx, *task)
+	return s.publishTask(ctx, EventTaskCreated, created, err)
+}
+
+// newTask validates req and builds the task to insert.
+func (s *Service) newTask(ctx context.Context, req CreateTaskRequest) (*Task, error) {
+	task, err := s.buildTask(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureProject(ctx, req.ProjectID); err != nil {
+		return nil, err
+	}
+	if task.UID != "" {
+		taken, err := s.takenUIDs(ctx, []string{task.UID})
+		if err != nil {
+			return nil, err
+		}
+		if taken[task.UID] {
+			return nil, &ConflictError{Message: fmt.Sprintf("a task with UID %q already exists", task.UID)}
+		}
+	}
+	return task, nil
+}
+
+// buildTask checks the fields of req that do not depend on other
+// documents and builds the task to insert.
+func (s *Service) buildTask(req CreateTaskRequest) (*Task, error) {
 	title := strings.TrimSpace(req.Title)
 	if len(title) < 3 {
 		return nil, &ValidationError{
@@ -87,32 +281,110 @@ func (s *Service) Create(ctx context.Context, req CreateTaskRequest) (*Task, err
 	if req.Done != nil {
 		done = *req.Done
 	}
+	if err := validateDescription(req.Description); err != nil {
+		return nil, err
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	if err := validatePriority("priority", priority); err != nil {
+		return nil, err
+	}
+	dueAt, remindAt := utcPtr(req.DueAt), utcPtr(req.RemindAt)
+	if err := validateSchedule(dueAt, remindAt); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.UID) > MaxUIDLength {
+		return nil, &ValidationError{Field: "uid", Message: fmt.Sprintf("uid must be at most %d characters", MaxUIDLength), Value: req.UID}
+	}
 
 	task := Task{
 		Title:        title,
+		Description:  req.Description,
 		Done:         done,
-		Tags:         req.Tags,
+		Priority:     priority,
+		ProjectID:    req.ProjectID,
+		Tags:         tags,
 		CreatedAt:    s.now().UTC(),
+		DueAt:        dueAt,
+		RemindAt:     remindAt,
+		UID:          req.UID,
 		Internal:     "internal",
 		internalNote: "ignored",
 	}
-
-	return s.repo.Create(ctx, task)
+	if done {
+		completedAt := task.CreatedAt
+		task.CompletedAt = &completedAt
+	}
+	return &task, nil
 }
 
 func (s *Service) Get(ctx context.Context, id string) (*Task, error) {
 	return s.repo.Get(ctx, id)
 }
 
-func (s *Service) List(ctx context.Context, filter TaskFilter) ([]Task, error) {
-	return s.repo.List(ctx, filter)
+func (s *Service) List(ctx context.Context, filter TaskFilter, opts ListOptions) (*TaskPage, error) {
+	if opts.Limit == 0 {
+		opts.Limit = DefaultPageLimit
+	}
+	if opts.Limit < 0 || opts.Limit > MaxPageLimit {
+		return nil, &ValidationError{
+			Field:   "limit",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxPageLimit),
+			Value:   opts.Limit,
+		}
+	}
+	if err := validateFilter(&filter); err != nil {
+		return nil, err
+	}
+	opts.Sort = strings.TrimSpace(opts.Sort)
+	opts.Cursor = strings.TrimSpace(opts.Cursor)
+
+	return s.repo.List(ctx, filter, opts)
 }
 
 func (s *Service) Update(ctx context.Context, id string, req UpdateTaskRequest) (*Task, error) {
+	if err := validateUpdate(&req); err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkUpdate(ctx, current, &req); err != nil {
+			return nil, err
+		}
+
+		// The write is guarded by the version that was read, so the revision
+		// compares against what the update actually replaced.
+		guarded := req
+		guarded.IfVersion = &current.Version
+		task, err := s.repo.Update(ctx, id, guarded)
+		var mismatch *VersionMismatchError
+		if errors.As(err, &mismatch) && req.IfVersion == nil && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.recordRevision(ctx, current, task)
+		return s.publishTask(ctx, EventTaskUpdated, task, nil)
+	}
+}
+
+// validateUpdate checks the fields of req that do not depend on the task
+// being updated, trimming the title and normalizing the tags in place.
+func validateUpdate(req *UpdateTaskRequest) error {
 	if req.Title != nil {
 		trimmed := strings.TrimSpace(*req.Title)
 		if len(trimmed) < 3 {
-			return nil, &ValidationError{
+			return &ValidationError{
 				Field:   "title",
 				Message: "title must be at least 3 characters",
 				Value:   *req.Title,
@@ -120,18 +392,151 @@ func (s *Service) Update(ctx context.Context, id string, req UpdateTaskRequest)
 		}
 		req.Title = &trimmed
 	}
-	if req.Title == nil && req.Done == nil && req.Tags == nil {
-		return nil, &ValidationError{
+	if req.Description != nil {
+		if err := validateDescription(*req.Description); err != nil {
+			return err
+		}
+	}
+	if req.Priority != nil {
+		if err := validatePriority("priority", *req.Priority); err != nil {
+			return err
+		}
+	}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = &tags
+	}
+	if req.Title == nil && req.Description == nil && req.Done == nil && req.Priority == nil && req.ProjectID == nil && req.Tags == nil && req.DueAt == nil && req.RemindAt == nil {
+		return &ValidationError{
 			Field:   "body",
 			Message: "at least one field must be provided",
 		}
 	}
+	return nil
+}
 
-	return s.repo.Update(ctx, id, req)
+// checkUpdate checks req against current, the task it updates, and
+// normalizes its timestamps to UTC.
+func (s *Service) checkUpdate(ctx context.Context, current *Task, req *UpdateTaskRequest) error {
+	if req.IfVersion != nil && *req.IfVersion != current.Version {
+		return &VersionMismatchError{Current: current.Version}
+	}
+	if req.DueAt != nil || req.RemindAt != nil {
+		req.DueAt, req.RemindAt = utcPtr(req.DueAt), utcPtr(req.RemindAt)
+		dueAt, remindAt := req.DueAt, req.RemindAt
+		if dueAt == nil {
+			dueAt = current.DueAt
+		}
+		if remindAt == nil {
+			remindAt = current.RemindAt
+		}
+		if err := validateSchedule(dueAt, remindAt); err != nil {
+			return err
+		}
+	}
+	if req.ProjectID != nil {
+		if err := s.ensureProject(ctx, *req.ProjectID); err != nil {
+			return err
+		}
+	}
+	if req.Done != nil && *req.Done {
+		if err := s.ensureUnblocked(ctx, current); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *Service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+func (s *Service) Delete(ctx context.Context, id string, ifVersion *int64) error {
+	if err := s.repo.Delete(ctx, id, ifVersion); err != nil {
+		return err
+	}
+	s.publish(ctx, s.newEvent(EventTaskDeleted, id, nil))
+	return nil
+}
+
+// validateFilter checks filter and normalizes its tag and search query in
+// place.
+func validateFilter(filter *TaskFilter) error {
+	for _, priority := range filter.Priorities {
+		if err := validatePriority("priority", priority); err != nil {
+			return err
+		}
+	}
+	if filter.DueBefore != nil && filter.DueAfter != nil && !filter.DueAfter.Before(*filter.DueBefore) {
+		return &ValidationError{
+			Field:   "dueAfter",
+			Message: "dueAfter must be before dueBefore",
+			Value:   *filter.DueAfter,
+		}
+	}
+	if filter.CreatedBefore != nil && filter.CreatedAfter != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return &ValidationError{
+			Field:   "createdAfter",
+			Message: "createdAfter must be before createdBefore",
+			Value:   *filter.CreatedAfter,
+		}
+	}
+	if filter.Tag != "" {
+		tag, err := normalizeTag("tag", filter.Tag)
+		if err != nil {
+			return err
+		}
+		filter.Tag = tag
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	if len(filter.Query) > MaxQueryLength {
+		return &ValidationError{
+			Field:   "q",
+			Message: fmt.Sprintf("q must be at most %d characters", MaxQueryLength),
+			Value:   filter.Query,
+		}
+	}
+	return nil
+}
+
+func validateDescription(description string) error {
+	if len(description) > MaxDescriptionLength {
+		return &ValidationError{
+			Field:   "description",
+			Message: fmt.Sprintf("description must be at most %d bytes", MaxDescriptionLength),
+		}
+	}
+	return nil
+}
+
+func validatePriority(field string, priority Priority) error {
+	if !priority.Valid() {
+		return &ValidationError{
+			Field:   field,
+			Message: "priority must be one of low, normal, high, urgent",
+			Value:   priority,
+		}
+	}
+	return nil
+}
+
+// validateSchedule rejects reminders that fire after the task is due.
+func validateSchedule(dueAt, remindAt *time.Time) error {
+	if dueAt != nil && remindAt != nil && remindAt.After(*dueAt) {
+		return &ValidationError{
+			Field:   "remindAt",
+			Message: "remindAt must not be after dueAt",
+			Value:   *remindAt,
+		}
+	}
+	return nil
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC()
+	return &value
 }
 
 func (s *Service) Ping(ctx context.Context) error {
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeBucket is the width of a time series bucket, named after the
+// $dateTrunc unit that computes it.
+type TimeBucket string
+
+const (
+	BucketHour  TimeBucket = "hour"
+	BucketDay   TimeBucket = "day"
+	BucketWeek  TimeBucket = "week"
+	BucketMonth TimeBucket = "month"
+)
+
+const (
+	MaxTimeseriesBuckets     = 1000
+	defaultTimeseriesBuckets = 30
+)
+
+// TimeseriesQuery selects the buckets of a time series. Buckets start at
+// midnight, or on the hour, in Location; weeks start on Monday.
+type TimeseriesQuery struct {
+	Bucket   TimeBucket
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+}
+
+type TimeseriesPoint struct {
+	Start     time.Time `json:"start" bson:"_id"`
+	Created   int64     `json:"created" bson:"created"`
+	Completed int64     `json:"completed" bson:"completed"`
+}
+
+type Timeseries struct {
+	Bucket   TimeBucket        `json:"bucket"`
+	Timezone string            `json:"timezone"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Items    []TimeseriesPoint `json:"items"`
+}
+
+// TimeseriesRepository counts tasks per time bucket.
+type TimeseriesRepository interface {
+	// Timeseries counts the tasks created and the tasks completed in each
+	// bucket between from and to. Buckets without tasks are left out.
+	Timeseries(ctx context.Context, bucket TimeBucket, from, to time.Time, loc *time.Location) ([]TimeseriesPoint, error)
+}
+
+// Timeseries returns how many tasks were created and completed in each
+// bucket of the query range, empty buckets included. The range defaults to
+// the last 30 buckets up to now.
+func (s *Service) Timeseries(ctx context.Context, q TimeseriesQuery) (*Timeseries, error) {
+	switch q.Bucket {
+	case "":
+		q.Bucket = BucketDay
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		return nil, &ValidationError{Field: "bucket", Message: "bucket must be one of hour, day, week, month", Value: q.Bucket}
+	}
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	to := s.now()
+	if q.To != nil {
+		to = *q.To
+	}
+	var from time.Time
+	if q.From != nil {
+		from = *q.From
+	} else {
+		from = addBuckets(truncateBucket(to, q.Bucket, loc), q.Bucket, loc, 1-defaultTimeseriesBuckets)
+	}
+	if !from.Before(to) {
+		return nil, &ValidationError{Field: "from", Message: "from must be before to", Value: from}
+	}
+
+	starts := []time.Time{}
+	for start := truncateBucket(from, q.Bucket, loc); start.Before(to); start = addBuckets(start, q.Bucket, loc, 1) {
+		if len(starts) == MaxTimeseriesBuckets {
+			return nil, &ValidationError{
+				Field:   "from",
+				Message: fmt.Sprintf("the range must span at most %d buckets", MaxTimeseriesBuckets),
+				Value:   from,
+			}
+		}
+		starts = append(starts, start)
+	}
+
+	points, err := s.repo.Timeseries(ctx, q.Bucket, from.UTC(), to.UTC(), loc)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]TimeseriesPoint, len(points))
+	for _, point := range points {
+		counts[point.Start.Unix()] = point
+	}
+
+	series := &Timeseries{
+		Bucket:   q.Bucket,
+		Timezone: loc.String(),
+		From:     from.UTC(),
+		To:       to.UTC(),
+		Items:    make([]TimeseriesPoint, 0, len(starts)),
+	}
+	for _, start := range starts {
+		point := counts[start.Unix()]
+		point.Start = start
+		series.Items = append(series.Items, point)
+	}
+	return series, nil
+}
+
+// truncateBucket returns the start of the bucket holding t, in loc, the way
+// $dateTrunc computes it.
+func truncateBucket(t time.Time, bucket TimeBucket, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch bucket {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// addBuckets moves start, a bucket start, n buckets forward or back.
+func addBuckets(start time.Time, bucket TimeBucket, loc *time.Location, n int) time.Time {
+	switch bucket {
+	case BucketHour:
+		return truncateBucket(start.Add(time.Duration(n)*time.Hour), bucket, loc)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7*n)
+	case BucketMonth:
+		return start.AddDate(0, n, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
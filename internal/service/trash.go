@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// Trash lists the tasks that were deleted but not purged yet.
+func (s *Service) Trash(ctx context.Context, opts ListOptions) (*TaskPage, error) {
+	return s.List(ctx, TaskFilter{Trashed: true}, opts)
+}
+
+// Restore moves a task out of the trash. A task whose project was deleted
+// in the meantime is restored without a project. Webhooks see the restore
+// as an update.
+func (s *Service) Restore(ctx context.Context, id string) (*Task, error) {
+	task, err := s.restore(ctx, id)
+	return s.publishTask(ctx, EventTaskUpdated, task, err)
+}
+
+func (s *Service) restore(ctx context.Context, id string) (*Task, error) {
+	task, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.ProjectID == "" {
+		return task, nil
+	}
+	_, err = s.projects.Get(ctx, task.ProjectID)
+	switch {
+	case err == nil:
+		return task, nil
+	case !errors.Is(err, ErrProjectNotFound):
+		return nil, err
+	}
+	noProject := ""
+	return s.repo.Update(ctx, id, UpdateTaskRequest{ProjectID: &noProject})
+}
+
+func (s *Service) Purge(ctx context.Context, id string) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+	s.publish(ctx, s.newEvent(EventTaskPurged, id, nil))
+	return nil
+}
+
+// EmptyTrash purges every task in the trash. When it fails partway, the
+// tasks purged until then still get their events.
+func (s *Service) EmptyTrash(ctx context.Context) (int64, error) {
+	ids, err := s.repo.EmptyTrash(ctx)
+	events := make([]Event, len(ids))
+	for i, id := range ids {
+		events[i] = s.newEvent(EventTaskPurged, id, nil)
+	}
+	s.publish(ctx, events...)
+	return int64(len(ids)), err
+}
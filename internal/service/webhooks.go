@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	MaxWebhookURLLength  = 2048
+	MinWebhookSecretSize = 16
+	MaxWebhookSecretSize = 256
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+	// webhookSecretBytes is the size of the secrets generated for webhooks
+	// created without one.
+	webhookSecretBytes = 32
+)
+
+var (
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrInvalidWebhookID  = errors.New("invalid webhook id")
+	ErrDeliveryNotFound  = errors.New("delivery not found")
+	ErrInvalidDeliveryID = errors.New("invalid delivery id")
+	// ErrDeliveryLeaseLost means the lease of a delivery expired, and the
+	// delivery may have been claimed again, before an attempt was recorded.
+	ErrDeliveryLeaseLost = errors.New("delivery lease lost")
+)
+
+type EventType string
+
+const (
+	EventTaskCreated EventType = "task.created"
+	EventTaskUpdated EventType = "task.updated"
+	EventTaskDeleted EventType = "task.deleted"
+	// EventTaskPurged follows task.deleted when the task leaves the trash
+	// for good.
+	EventTaskPurged EventType = "task.purged"
+)
+
+// EventTypes lists the events webhooks can subscribe to.
+var EventTypes = []EventType{EventTaskCreated, EventTaskUpdated, EventTaskDeleted, EventTaskPurged}
+
+// Event is the payload of a delivery. Task is the task right after the
+// change and is nil for task.deleted and task.purged. A redelivery sends
+// the same event, so receivers can drop events whose ID they have already
+// seen.
+type Event struct {
+	ID        string    `json:"id" bson:"id"`
+	Type      EventType `json:"type" bson:"type"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	TaskID    string    `json:"taskId" bson:"taskId"`
+	Task      *Task     `json:"task,omitempty" bson:"task,omitempty"`
+}
+
+// Webhook subscribes URL to events. Deliveries are signed with Secret,
+// which is only shown when the webhook is created.
+type Webhook struct {
+	ID        string      `json:"id" bson:"_id,omitempty"`
+	URL       string      `json:"url" bson:"url"`
+	Events    []EventType `json:"events" bson:"events"`
+	Active    bool        `json:"active" bson:"active"`
+	Secret    string      `json:"-" bson:"secret"`
+	CreatedAt time.Time   `json:"createdAt" bson:"createdAt"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string
+	Events []EventType
+	// Secret is generated when empty.
+	Secret string
+	// Active defaults to true.
+	Active *bool
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string
+	Events []EventType
+	Secret *string
+	Active *bool
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is one event queued for one webhook. A pending delivery is
+// attempted at NextAttemptAt; it ends as succeeded or, once its attempts
+// are used up, as failed. RedeliveryOf is set on deliveries created by
+// Redeliver.
+type Delivery struct {
+	ID             string         `json:"id"`
+	WebhookID      string         `json:"webhookId"`
+	Event          Event          `json:"event"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time     `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int            `json:"responseStatus,omitempty" doc:"HTTP status of the last attempt"`
+	LastError      string         `json:"lastError,omitempty"`
+	RedeliveryOf   string         `json:"redeliveryOf,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	// Lease identifies the claim that returned the delivery; only that
+	// claim can record the attempt.
+	Lease string `json:"-"`
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once. Status is
+// pending when the delivery will be retried at NextAttemptAt.
+type DeliveryAttempt struct {
+	At             time.Time
+	Status         DeliveryStatus
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  *time.Time
+}
+
+// DeliveryRepository stores deliveries. ListDeliveries returns the newest
+// first, only those with status when it is set. ClaimDelivery takes the
+// pending delivery that is due the longest, moves its NextAttemptAt to
+// leaseUntil, so that no other dispatcher takes it meanwhile, and marks it
+// with lease; it returns nil when none is due. RecordAttempt only records
+// an attempt while the delivery still carries lease, and fails with
+// ErrDeliveryLeaseLost otherwise.
+type DeliveryRepository interface {
+	CreateDeliveries(ctx context.Context, deliveries []Delivery) error
+	GetDelivery(ctx context.Context, webhookID, id string) (*Delivery, error)
+	ListDeliveries(ctx context.Context, webhookID string, status DeliveryStatus, limit int) ([]Delivery, error)
+	ClaimDelivery(ctx context.Context, now, leaseUntil time.Time, lease string) (*Delivery, error)
+	RecordAttempt(ctx context.Context, id, lease string, attempt DeliveryAttempt) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook Webhook) (*Webhook, error)
+	Get(ctx context.Context, id string) (*Webhook, error)
+	List(ctx context.Context) ([]Webhook, error)
+	Update(ctx context.Context, id string, update UpdateWebhookRequest) (*Webhook, error)
+	Delete(ctx context.Context, id string) error
+	// ListActive returns the webhooks that receive events.
+	ListActive(ctx context.Context) ([]Webhook, error)
+	DeliveryRepository
+}
+
+func (s *Service) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*Webhook, error) {
+	webhookURL, err := validateWebhookURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		secret = newRandomHex(webhookSecretBytes)
+	} else if err := validateWebhookSecret(secret); err != nil {
+		return nil, err
+	}
+	return s.webhooks.Create(ctx, Webhook{
+		URL:       webhookURL,
+		Events:    events,
+		Active:    req.Active == nil || *req.Active,
+		Secret:    secret,
+		CreatedAt: s.now().UTC(),
+	})
+}
+
+func (s *Service) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	return s.webhooks.Get(ctx, id)
+}
+
+func (s *Service) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	return s.webhooks.List(ctx)
+}
+
+func (s *Service) UpdateWebhook(ctx context.Context, id string, req UpdateWebhookRequest) (*Webhook, error) {
+	if req.URL == nil && req.Events == nil && req.Secret == nil && req.Active == nil {
+		return nil, &ValidationError{
+			Field:   "body",
+			Message: "at least one field must be provided",
+		}
+	}
+	if req.URL != nil {
+		webhookURL, err := validateWebhookURL(*req.URL)
+		if err != nil {
+			return nil, err
+		}
+		req.URL = &webhookURL
+	}
+	if req.Events != nil {
+		events, err := validateEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		req.Events = events
+	}
+	if req.Secret != nil {
+		if err := validateWebhookSecret(*req.Secret); err != nil {
+			return nil, err
+		}
+	}
+	return s.webhooks.Update(ctx, id, req)
+}
+
+// DeleteWebhook removes a webhook. Its pending deliveries fail when their
+// turn comes.
+func (s *Service) DeleteWebhook(ctx context.Context, id string) error {
+	return s.webhooks.Delete(ctx, id)
+}
+
+func (s *Service) ListDeliveries(ctx context.Context, webhookID string, status DeliveryStatus, limit int) ([]Delivery, error) {
+	if limit == 0 {
+		limit = DefaultDeliveryLimit
+	}
+	if limit < 0 || limit > MaxDeliveryLimit {
+		return nil, &ValidationError{
+			Field:   "limit",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxDeliveryLimit),
+			Value:   limit,
+		}
+	}
+	switch status {
+	case "", DeliveryPending, DeliverySucceeded, DeliveryFailed:
+	default:
+		return nil, &ValidationError{
+			Field:   "status",
+			Message: "status must be pending, succeeded or failed",
+			Value:   status,
+		}
+	}
+	if _, err := s.webhooks.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.ListDeliveries(ctx, webhookID, status, limit)
+}
+
+func (s *Service) GetDelivery(ctx context.Context, webhookID, id string) (*Delivery, error) {
+	return s.webhooks.GetDelivery(ctx, webhookID, id)
+}
+
+// Redeliver queues the event of a finished delivery again as a new
+// delivery, which is attempted right away with a fresh set of attempts.
+func (s *Service) Redeliver(ctx context.Context, webhookID, id string) (*Delivery, error) {
+	if _, err := s.webhooks.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	original, err := s.webhooks.GetDelivery(ctx, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == DeliveryPending {
+		return nil, &ConflictError{Message: "delivery is still pending"}
+	}
+	delivery := newDelivery(webhookID, original.Event, s.now().UTC())
+	delivery.RedeliveryOf = original.ID
+	deliveries := []Delivery{delivery}
+	if err := s.webhooks.CreateDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// ClaimDelivery takes the next due delivery for a dispatcher, along with
+// its webhook, and keeps it from other dispatchers for lease. A delivery
+// whose webhook was deleted or deactivated fails without being sent. It
+// returns nil when no delivery is due.
+func (s *Service) ClaimDelivery(ctx context.Context, lease time.Duration) (*Delivery, *Webhook, error) {
+	for {
+		now := s.now().UTC()
+		delivery, err := s.webhooks.ClaimDelivery(ctx, now, now.Add(lease), newRandomHex(16))
+		if err != nil || delivery == nil {
+			return nil, nil, err
+		}
+		webhook, err := s.webhooks.Get(ctx, delivery.WebhookID)
+		switch {
+		case err == nil && webhook.Active:
+			return delivery, webhook, nil
+		case err == nil:
+			err = s.webhooks.RecordAttempt(ctx, delivery.ID, delivery.Lease, DeliveryAttempt{At: now, Status: DeliveryFailed, Error: "webhook is inactive"})
+		case errors.Is(err, ErrWebhookNotFound):
+			err = s.webhooks.RecordAttempt(ctx, delivery.ID, delivery.Lease, DeliveryAttempt{At: now, Status: DeliveryFailed, Error: "webhook was deleted"})
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// RecordAttempt stores the outcome of sending a delivery claimed with
+// ClaimDelivery. It fails with ErrDeliveryLeaseLost when the lease of the
+// claim ran out first, so that an attempt is never counted twice.
+func (s *Service) RecordAttempt(ctx context.Context, delivery *Delivery, attempt DeliveryAttempt) error {
+	return s.webhooks.RecordAttempt(ctx, delivery.ID, delivery.Lease, attempt)
+}
+
+// publishTask publishes an event of type eventType for task once the
+// change that returned task and err succeeded.
+func (s *Service) publishTask(ctx context.Context, eventType EventType, task *Task, err error) (*Task, error) {
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, s.newEvent(eventType, task.ID, task))
+	return task, nil
+}
+
+// publish queues a delivery of each event to every active webhook that
+// subscribes to it. The events describe writes that already landed, so a
+// failure to queue them is logged rather than failing the write, whose
+// caller could otherwise retry it and apply it twice.
+func (s *Service) publish(ctx context.Context, events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	if err := s.queueDeliveries(context.WithoutCancel(ctx), events); err != nil {
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		slog.Error("events not published", "err", err, "event_ids", ids)
+	}
+}
+
+func (s *Service) queueDeliveries(ctx context.Context, events []Event) error {
+	webhooks, err := s.webhooks.ListActive(ctx)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+	var deliveries []Delivery
+	for _, event := range events {
+		for _, webhook := range webhooks {
+			if slices.Contains(webhook.Events, event.Type) {
+				deliveries = append(deliveries, newDelivery(webhook.ID, event, event.CreatedAt))
+			}
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.webhooks.CreateDeliveries(ctx, deliveries)
+}
+
+func (s *Service) newEvent(eventType EventType, taskID string, task *Task) Event {
+	return Event{ID: newRandomHex(16), Type: eventType, CreatedAt: s.now().UTC(), TaskID: taskID, Task: task}
+}
+
+func newDelivery(webhookID string, event Event, now time.Time) Delivery {
+	return Delivery{
+		WebhookID:     webhookID,
+		Event:         event,
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+}
+
+func validateWebhookURL(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	parsed, err := url.Parse(trimmed)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", &ValidationError{Field: "url", Message: "url must be an absolute http or https URL", Value: raw}
+	}
+	if len(trimmed) > MaxWebhookURLLength {
+		return "", &ValidationError{
+			Field:   "url",
+			Message: fmt.Sprintf("url must be at most %d characters", MaxWebhookURLLength),
+			Value:   raw,
+		}
+	}
+	return trimmed, nil
+}
+
+// eventTypeList joins EventTypes for error messages.
+func eventTypeList() string {
+	names := make([]string, len(EventTypes))
+	for i, event := range EventTypes {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ", ")
+}
+
+func validateEvents(events []EventType) ([]EventType, error) {
+	if len(events) == 0 {
+		return nil, &ValidationError{Field: "events", Message: "events must name at least one event"}
+	}
+	unique := make([]EventType, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(EventTypes, event) {
+			return nil, &ValidationError{
+				Field:   "events",
+				Message: "events must be one of " + eventTypeList(),
+				Value:   event,
+			}
+		}
+		if !slices.Contains(unique, event) {
+			unique = append(unique, event)
+		}
+	}
+	return unique, nil
+}
+
+func validateWebhookSecret(secret string) error {
+	if len(secret) < MinWebhookSecretSize || len(secret) > MaxWebhookSecretSize {
+		return &ValidationError{
+			Field:   "secret",
+			Message: fmt.Sprintf("secret must be between %d and %d bytes", MinWebhookSecretSize, MaxWebhookSecretSize),
+		}
+	}
+	return nil
+}
+
+func newRandomHex(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b) // never fails, see crypto/rand.Read
+	return hex.EncodeToString(b)
+}
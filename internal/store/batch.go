@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// maxWriteTokens is how many batch write tokens a task keeps, see
+// batchMisses.
+const maxWriteTokens = 16
+
+// batchFailure carries the per-write errors out of an aborted transaction.
+type batchFailure struct {
+	errs []error
+}
+
+func (f *batchFailure) Error() string {
+	return "batch aborted"
+}
+
+// ApplyBatch sends every write in one BulkWrite. Best-effort batches use an
+// unordered bulk write, so one failing write does not stop the others;
+// atomic batches run an ordered bulk write inside a transaction, which
+// needs a replica set.
+func (r *MongoTaskRepository) ApplyBatch(ctx context.Context, writes []service.BatchWrite, atomic bool) ([]error, error) {
+	errs, err := r.applyBatch(ctx, writes, atomic)
+	// An error may come after some writes landed, so only a batch known to
+	// have changed nothing leaves the counter alone.
+	if err != nil || slices.Contains(errs, nil) {
+		r.recordChange(ctx)
+	}
+	return errs, err
+}
+
+func (r *MongoTaskRepository) applyBatch(ctx context.Context, writes []service.BatchWrite, atomic bool) ([]error, error) {
+	models := make([]mongo.WriteModel, 0, len(writes))
+	tokens := make([]primitive.ObjectID, len(writes))
+	now := time.Now().UTC()
+	for i := range writes {
+		tokens[i] = primitive.NewObjectID()
+		model, err := batchModel(&writes[i], tokens[i], now)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if !atomic {
+		res, err := r.collection.BulkWrite(opCtx, models, options.BulkWrite().SetOrdered(false))
+		errs, err := bulkWriteErrors(len(writes), err)
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount < expectedMatches(writes, errs) {
+			if err := r.batchMisses(opCtx, writes, tokens, errs); err != nil {
+				return nil, err
+			}
+		}
+		return errs, nil
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(opCtx)
+	_, err = session.WithTransaction(opCtx, func(sc mongo.SessionContext) (any, error) {
+		res, err := r.collection.BulkWrite(sc, models)
+		errs, err := bulkWriteErrors(len(writes), err)
+		if err != nil {
+			return nil, err
+		}
+		if hasError(errs) {
+			return nil, &batchFailure{errs: errs}
+		}
+		if res.MatchedCount < expectedMatches(writes, errs) {
+			if err := r.batchMisses(sc, writes, tokens, errs); err != nil {
+				return nil, err
+			}
+			return nil, &batchFailure{errs: errs}
+		}
+		return nil, nil
+	})
+	var failure *batchFailure
+	switch {
+	case errors.As(err, &failure):
+		for i := range failure.errs {
+			if failure.errs[i] == nil {
+				failure.errs[i] = service.ErrBatchAborted
+			}
+		}
+		return failure.errs, nil
+	case err != nil:
+		return nil, err
+	}
+	return make([]error, len(writes)), nil
+}
+
+// batchModel turns write into a write model. Updates and deletes also push
+// token onto the writeTokens of their task, keeping the last
+// maxWriteTokens, so that batchMisses can tell whether they applied.
+func batchModel(write *service.BatchWrite, token primitive.ObjectID, now time.Time) (mongo.WriteModel, error) {
+	pushToken := bson.E{Key: "$push", Value: bson.M{"writeTokens": bson.M{"$each": bson.A{token}, "$slice": -maxWriteTokens}}}
+	switch write.Kind {
+	case service.BatchCreate:
+		doc, err := newTaskDocument(write.Task)
+		if err != nil {
+			return nil, err
+		}
+		write.ID = doc.ID.Hex()
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	case service.BatchUpdate:
+		objID, err := parseObjectID(write.ID)
+		if err != nil {
+			return nil, err
+		}
+		update, err := taskUpdate(write.Update, now)
+		if err != nil {
+			return nil, err
+		}
+		return mongo.NewUpdateOneModel().
+			SetFilter(withVersion(activeByID(objID), write.IfVersion)).
+			SetUpdate(append(update, pushToken)), nil
+	case service.BatchDelete:
+		objID, err := parseObjectID(write.ID)
+		if err != nil {
+			return nil, err
+		}
+		return mongo.NewUpdateOneModel().
+			SetFilter(withVersion(activeByID(objID), write.IfVersion)).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.M{"deletedAt": now}}, bumpVersion, pushToken}), nil
+	default:
+		return nil, fmt.Errorf("unknown batch operation %q", write.Kind)
+	}
+}
+
+// bulkWriteErrors spreads the write errors of a BulkWriteException over the
+// writes they belong to. Any other error is returned as is.
+func bulkWriteErrors(n int, err error) ([]error, error) {
+	errs := make([]error, n)
+	if err == nil {
+		return errs, nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return nil, err
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		errs[writeErr.Index] = fmt.Errorf("write failed: %s", writeErr.Message)
+	}
+	return errs, nil
+}
+
+// expectedMatches counts the updates and deletes that did not fail, each
+// of which should have matched one task.
+func expectedMatches(writes []service.BatchWrite, errs []error) int64 {
+	var n int64
+	for i, write := range writes {
+		if write.Kind != service.BatchCreate && errs[i] == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func hasError(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// batchMisses finds the updates and deletes that matched no task, because
+// the task was deleted or its version changed after the batch was
+// validated, and records why in errs. A write applied when its token is
+// among the writeTokens of its task; only maxWriteTokens later batch
+// writes to the same task, landing before the check, could hide it.
+func (r *MongoTaskRepository) batchMisses(ctx context.Context, writes []service.BatchWrite, tokens []primitive.ObjectID, errs []error) error {
+	var ids []primitive.ObjectID
+	for i, write := range writes {
+		if write.Kind != service.BatchCreate && errs[i] == nil {
+			objID, _ := primitive.ObjectIDFromHex(write.ID)
+			ids = append(ids, objID)
+		}
+	}
+
+	cur, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"version": 1, "deletedAt": 1, "writeTokens": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	type writeState struct {
+		ID          primitive.ObjectID   `bson:"_id"`
+		Version     int64                `bson:"version"`
+		DeletedAt   *time.Time           `bson:"deletedAt"`
+		WriteTokens []primitive.ObjectID `bson:"writeTokens"`
+	}
+	current := map[string]writeState{}
+	for cur.Next(ctx) {
+		var doc writeState
+		if err := cur.Decode(&doc); err != nil {
+			return err
+		}
+		current[doc.ID.Hex()] = doc
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	for i, write := range writes {
+		if write.Kind == service.BatchCreate || errs[i] != nil {
+			continue
+		}
+		doc, ok := current[write.ID]
+		switch {
+		case ok && slices.Contains(doc.WriteTokens, tokens[i]):
+		case !ok || doc.DeletedAt != nil:
+			errs[i] = service.ErrNotFound
+		default:
+			errs[i] = &service.VersionMismatchError{Current: doc.Version}
+		}
+	}
+	return nil
+}
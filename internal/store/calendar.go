@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+func (r *MongoTaskRepository) TakenUIDs(ctx context.Context, uids []string) ([]string, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, bson.M{"uid": bson.M{"$in": uids}},
+		options.Find().SetProjection(bson.M{"uid": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	var taken []string
+	for cur.Next(opCtx) {
+		var doc taskDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		taken = append(taken, doc.UID)
+	}
+	return taken, cur.Err()
+}
+
+func (r *MongoTaskRepository) ExistingIDs(ctx context.Context, ids []string) ([]string, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := parseObjectID(id)
+		if err != nil {
+			return nil, err
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, bson.M{"_id": bson.M{"$in": objIDs}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	var existing []string
+	for cur.Next(opCtx) {
+		var doc taskDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		existing = append(existing, doc.ID.Hex())
+	}
+	return existing, cur.Err()
+}
+
+func (r *MongoTaskRepository) GetByUID(ctx context.Context, uid string) (*service.Task, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc taskDocument
+	if err := r.collection.FindOne(opCtx, bson.M{"uid": uid, "deletedAt": bson.M{"$exists": false}}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrNotFound
+		}
+		return nil, err
+	}
+
+	task := toTask(doc)
+	return &task, nil
+}
+
+// recordChange advances the change counter of the collection after a task
+// write. It runs detached from ctx, since the write has landed even when
+// the client is gone. A failure is only logged: the write succeeded, and
+// the next one advances the counter past it.
+func (r *MongoTaskRepository) recordChange(ctx context.Context) {
+	opCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	defer cancel()
+	if _, err := r.counters.UpdateOne(opCtx,
+		bson.M{"_id": r.collection.Name()},
+		bson.M{"$inc": bson.M{"changes": 1}},
+		options.Update().SetUpsert(true),
+	); err != nil {
+		slog.Error("task change counter not advanced", "err", err)
+	}
+}
+
+func (r *MongoTaskRepository) ChangeCount(ctx context.Context) (int64, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc struct {
+		Changes int64 `bson:"changes"`
+	}
+	err := r.counters.FindOne(opCtx, bson.M{"_id": r.collection.Name()}).Decode(&doc)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+	return doc.Changes, nil
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+type checklistItemDocument struct {
+	ID   string `bson:"id"`
+	Text string `bson:"text"`
+	Done bool   `bson:"done"`
+}
+
+func (r *MongoTaskRepository) AddChecklistItem(ctx context.Context, taskID, text string, position *int) (*service.Task, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := checklistItemDocument{ID: primitive.NewObjectID().Hex(), Text: text}
+	push := bson.D{{Key: "$each", Value: bson.A{item}}}
+	if position != nil {
+		push = append(push, bson.E{Key: "$position", Value: *position})
+	}
+	// The size guard is part of the filter so concurrent adds cannot
+	// overflow the checklist: the last free slot must still be empty.
+	filter := bson.D{
+		{Key: "_id", Value: objID},
+		{Key: "deletedAt", Value: bson.M{"$exists": false}},
+		{Key: checklistSlot(service.MaxChecklistItems - 1), Value: bson.M{"$exists": false}},
+	}
+	task, err := r.updateChecklist(ctx, filter, bson.D{{Key: "$push", Value: bson.D{{Key: "checklist", Value: push}}}, bumpVersion})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.guardMiss(ctx, objID, &service.ValidationError{
+			Field:   "checklist",
+			Message: "checklist is full",
+		})
+	}
+	return task, err
+}
+
+func (r *MongoTaskRepository) ToggleChecklistItem(ctx context.Context, taskID, itemID string) (*service.Task, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A pipeline update flips the flag server-side, so two concurrent
+	// toggles always cancel out instead of racing.
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"checklist": bson.M{"$map": bson.M{
+			"input": "$checklist",
+			"as":    "item",
+			"in": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$$item.id", itemID}},
+				bson.M{"$mergeObjects": bson.A{"$$item", bson.M{"done": bson.M{"$not": bson.A{"$$item.done"}}}}},
+				"$$item",
+			}},
+		}},
+		"version": bumpVersionExpr,
+	}}}}
+	task, err := r.updateChecklist(ctx, activeItemFilter(objID, itemID), update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.guardMiss(ctx, objID, service.ErrChecklistItemNotFound)
+	}
+	return task, err
+}
+
+func (r *MongoTaskRepository) ReorderChecklist(ctx context.Context, taskID string, itemIDs []string) (*service.Task, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := bson.A{}
+	for _, itemID := range itemIDs {
+		ids = append(ids, itemID)
+	}
+	// The filter only matches when itemIDs is a permutation of the current
+	// items, so the reorder cannot drop or duplicate an item added meanwhile.
+	filter := activeByID(objID)
+	if len(itemIDs) > 0 {
+		filter["checklist"] = bson.M{"$size": len(itemIDs)}
+		filter["checklist.id"] = bson.M{"$all": ids}
+	} else {
+		// A task that never had items has no checklist field at all.
+		filter["checklist"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"checklist": bson.M{"$map": bson.M{
+			"input": ids,
+			"as":    "itemId",
+			"in": bson.M{"$arrayElemAt": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": "$checklist",
+					"cond":  bson.M{"$eq": bson.A{"$$this.id", "$$itemId"}},
+				}},
+				0,
+			}},
+		}},
+		"version": bumpVersionExpr,
+	}}}}
+	task, err := r.updateChecklist(ctx, filter, update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.guardMiss(ctx, objID, &service.ValidationError{
+			Field:   "itemIds",
+			Message: "itemIds must list every checklist item exactly once",
+			Value:   itemIDs,
+		})
+	}
+	return task, err
+}
+
+func (r *MongoTaskRepository) DeleteChecklistItem(ctx context.Context, taskID, itemID string) (*service.Task, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.D{{Key: "$pull", Value: bson.M{"checklist": bson.M{"id": itemID}}}, bumpVersion}
+	task, err := r.updateChecklist(ctx, activeItemFilter(objID, itemID), update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.guardMiss(ctx, objID, service.ErrChecklistItemNotFound)
+	}
+	return task, err
+}
+
+func (r *MongoTaskRepository) updateChecklist(ctx context.Context, filter, update any) (*service.Task, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc taskDocument
+	if err := r.collection.FindOneAndUpdate(opCtx, filter, update, opts).Decode(&doc); err != nil {
+		return nil, err
+	}
+	r.recordChange(ctx)
+	task := toTask(doc)
+	return &task, nil
+}
+
+// guardMiss explains why a guarded update of a task matched nothing:
+// either the task does not exist or the guard failed with reason.
+func (r *MongoTaskRepository) guardMiss(ctx context.Context, objID primitive.ObjectID, reason error) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	count, err := r.collection.CountDocuments(opCtx, activeByID(objID), options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return service.ErrNotFound
+	}
+	return reason
+}
+
+func activeItemFilter(objID primitive.ObjectID, itemID string) bson.M {
+	filter := activeByID(objID)
+	filter["checklist.id"] = itemID
+	return filter
+}
+
+// checklistSlot is the dotted path of the checklist element at index, used
+// to test the array length in a query filter.
+func checklistSlot(index int) string {
+	return "checklist." + strconv.Itoa(index)
+}
+
+func toChecklist(docs []checklistItemDocument) []service.ChecklistItem {
+	if len(docs) == 0 {
+		return nil
+	}
+	items := make([]service.ChecklistItem, 0, len(docs))
+	for _, doc := range docs {
+		items = append(items, service.ChecklistItem{ID: doc.ID, Text: doc.Text, Done: doc.Done})
+	}
+	return items
+}
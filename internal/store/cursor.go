@@ -0,0 +1,219 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	defaultSort       = "createdAt"
+	defaultSearchSort = "-score"
+)
+
+// sortField maps an API sort key to the document field it orders by. When
+// computed is set, the field is derived by an $addFields expression before
+// sorting rather than stored.
+type sortField struct {
+	column   string
+	computed any
+	value    func(doc taskDocument) any
+}
+
+// sortableFields is the allowlist of API sort keys and the document field
+// each one orders by.
+var sortableFields = map[string]sortField{
+	"createdAt": {column: "createdAt", value: func(doc taskDocument) any { return doc.CreatedAt }},
+	"title":     {column: "title", value: func(doc taskDocument) any { return doc.Title }},
+	"done":      {column: "done", value: func(doc taskDocument) any { return doc.Done }},
+	"score":     {column: "score", value: func(doc taskDocument) any { return doc.Score }},
+	"priority":  {column: "priorityRank", computed: priorityRankExpr(), value: func(doc taskDocument) any { return toPriority(doc.Priority).Rank() }},
+}
+
+// priorityRankExpr orders priorities by urgency instead of by name. Tasks
+// stored before priorities existed rank as normal.
+func priorityRankExpr() bson.M {
+	branches := bson.A{}
+	for rank, priority := range service.Priorities {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$eq": bson.A{"$priority", string(priority)}},
+			"then": rank,
+		})
+	}
+	return bson.M{"$switch": bson.M{
+		"branches": branches,
+		"default":  service.PriorityNormal.Rank(),
+	}}
+}
+
+type sortKey struct {
+	name string
+	desc bool
+}
+
+// sortSpec is a parsed sort parameter. _id is always appended as the final
+// ascending tiebreaker so the ordering is total and pages are stable.
+type sortSpec []sortKey
+
+// parseSort validates a sort parameter against sortableFields. The score
+// field only exists on full-text searches, which default to relevance order.
+func parseSort(value string, search bool) (sortSpec, error) {
+	if strings.TrimSpace(value) == "" {
+		value = defaultSort
+		if search {
+			value = defaultSearchSort
+		}
+	}
+
+	var spec sortSpec
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		key := sortKey{name: part}
+		if strings.HasPrefix(part, "-") {
+			key = sortKey{name: strings.TrimPrefix(part, "-"), desc: true}
+		} else if strings.HasPrefix(part, "+") {
+			key.name = strings.TrimPrefix(part, "+")
+		}
+		if key.name == "score" && !search {
+			return nil, &service.ValidationError{
+				Field:   "sort",
+				Message: "sorting by score requires a search query",
+				Value:   value,
+			}
+		}
+		if _, ok := sortableFields[key.name]; !ok {
+			return nil, &service.ValidationError{
+				Field:   "sort",
+				Message: fmt.Sprintf("unsupported sort field %q", key.name),
+				Value:   value,
+			}
+		}
+		if _, dup := seen[key.name]; dup {
+			return nil, &service.ValidationError{
+				Field:   "sort",
+				Message: fmt.Sprintf("duplicate sort field %q", key.name),
+				Value:   value,
+			}
+		}
+		seen[key.name] = struct{}{}
+		spec = append(spec, key)
+	}
+	return spec, nil
+}
+
+func (s sortSpec) String() string {
+	parts := make([]string, 0, len(s))
+	for _, key := range s {
+		if key.desc {
+			parts = append(parts, "-"+key.name)
+			continue
+		}
+		parts = append(parts, key.name)
+	}
+	return strings.Join(parts, ",")
+}
+
+// computedStage returns the $addFields stage for computed sort fields, or
+// nil when the sort only uses stored fields.
+func (s sortSpec) computedStage() bson.D {
+	fields := bson.M{}
+	for _, key := range s {
+		field := sortableFields[key.name]
+		if field.computed != nil {
+			fields[field.column] = field.computed
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return bson.D{{Key: "$addFields", Value: fields}}
+}
+
+func (s sortSpec) mongoSort() bson.D {
+	out := make(bson.D, 0, len(s)+1)
+	for _, key := range s {
+		dir := 1
+		if key.desc {
+			dir = -1
+		}
+		out = append(out, bson.E{Key: sortableFields[key.name].column, Value: dir})
+	}
+	return append(out, bson.E{Key: "_id", Value: 1})
+}
+
+// after builds the keyset predicate matching every document that sorts
+// strictly after the cursor position.
+func (s sortSpec) after(cursor pageCursor) bson.E {
+	branches := bson.A{}
+	equal := bson.D{}
+	for i, key := range s {
+		column := sortableFields[key.name].column
+		op := "$gt"
+		if key.desc {
+			op = "$lt"
+		}
+		branch := append(bson.D{}, equal...)
+		branch = append(branch, bson.E{Key: column, Value: bson.M{op: cursor.Values[i]}})
+		branches = append(branches, branch)
+		equal = append(equal, bson.E{Key: column, Value: cursor.Values[i]})
+	}
+	last := append(bson.D{}, equal...)
+	last = append(last, bson.E{Key: "_id", Value: bson.M{"$gt": cursor.ID}})
+	branches = append(branches, last)
+	return bson.E{Key: "$or", Value: branches}
+}
+
+// pageCursor is the position of the last item of a page. It is serialized
+// as base64url BSON so value types survive the round trip and clients treat
+// it as opaque.
+type pageCursor struct {
+	Sort   string             `bson:"s"`
+	Values []any              `bson:"v"`
+	ID     primitive.ObjectID `bson:"i"`
+}
+
+func encodeCursor(spec sortSpec, doc taskDocument) string {
+	cursor := pageCursor{Sort: spec.String(), ID: doc.ID}
+	for _, key := range spec {
+		cursor.Values = append(cursor.Values, sortableFields[key.name].value(doc))
+	}
+	payload, err := bson.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(spec sortSpec, value string) (pageCursor, error) {
+	invalid := &service.ValidationError{Field: "cursor", Message: "invalid cursor", Value: value}
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, invalid
+	}
+	var raw struct {
+		Sort   string             `bson:"s"`
+		Values bson.A             `bson:"v"`
+		ID     primitive.ObjectID `bson:"i"`
+	}
+	if err := bson.Unmarshal(payload, &raw); err != nil {
+		return pageCursor{}, invalid
+	}
+	if raw.Sort != spec.String() {
+		return pageCursor{}, &service.ValidationError{
+			Field:   "cursor",
+			Message: "cursor was issued for a different sort",
+			Value:   value,
+		}
+	}
+	if len(raw.Values) != len(spec) || raw.ID.IsZero() {
+		return pageCursor{}, invalid
+	}
+	return pageCursor{Sort: raw.Sort, Values: []any(raw.Values), ID: raw.ID}, nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+func (r *MongoTaskRepository) AddBlocker(ctx context.Context, taskID, blockerID string) (*service.Task, error) {
+	return r.updateBlockers(ctx, taskID, blockerID, "$addToSet")
+}
+
+func (r *MongoTaskRepository) RemoveBlocker(ctx context.Context, taskID, blockerID string) (*service.Task, error) {
+	return r.updateBlockers(ctx, taskID, blockerID, "$pull")
+}
+
+func (r *MongoTaskRepository) updateBlockers(ctx context.Context, taskID, blockerID, op string) (*service.Task, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	blockerObjID, err := parseObjectID(blockerID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := activeByID(objID)
+	if op == "$addToSet" {
+		// The size guard is part of the filter so concurrent adds cannot
+		// grow the list past MaxBlockers. A blocker that is already listed
+		// needs no free slot.
+		filter["$or"] = bson.A{
+			bson.M{blockerSlot(service.MaxBlockers - 1): bson.M{"$exists": false}},
+			bson.M{"blockedBy": blockerObjID},
+		}
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc taskDocument
+	if err := r.collection.FindOneAndUpdate(
+		opCtx,
+		filter,
+		bson.D{{Key: op, Value: bson.M{"blockedBy": blockerObjID}}, bumpVersion},
+		opts,
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, r.guardMiss(ctx, objID, &service.ValidationError{
+				Field:   "taskId",
+				Message: fmt.Sprintf("a task can have at most %d blockers", service.MaxBlockers),
+			})
+		}
+		return nil, err
+	}
+	r.recordChange(ctx)
+
+	task := toTask(doc)
+	return &task, nil
+}
+
+func (r *MongoTaskRepository) GetMany(ctx context.Context, ids []string) ([]service.Task, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := parseObjectID(id)
+		if err != nil {
+			return nil, err
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, bson.M{
+		"_id":       bson.M{"$in": objIDs},
+		"deletedAt": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	tasks := make([]service.Task, 0, len(ids))
+	for cur.Next(opCtx) {
+		var doc taskDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, toTask(doc))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// blockerSlot is the dotted path of the blockedBy element at index.
+func blockerSlot(index int) string {
+	return "blockedBy." + strconv.Itoa(index)
+}
+
+// blockedStages matches tasks by whether any of their blockers is still
+// open. It needs a $lookup, so it only applies to aggregation queries. The
+// lookup joins blockedBy on _id, so each blocker is found through the _id
+// index, and its pipeline (MongoDB 5.0+) keeps only the open ones.
+func (r *MongoTaskRepository) blockedStages(blocked bool) []bson.D {
+	match := bson.M{"openBlockers": bson.M{"$eq": bson.A{}}}
+	if blocked {
+		match = bson.M{"openBlockers": bson.M{"$ne": bson.A{}}}
+	}
+	return []bson.D{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         r.collection.Name(),
+			"localField":   "blockedBy",
+			"foreignField": "_id",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"done":      false,
+					"deletedAt": bson.M{"$exists": false},
+				}},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "openBlockers",
+		}}},
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{"openBlockers": 0}}},
+	}
+}
+
+// detachBlockers removes purged tasks from every blockedBy list so they do
+// not linger as dangling links.
+func (r *MongoTaskRepository) detachBlockers(ctx context.Context, objIDs []primitive.ObjectID) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.UpdateMany(opCtx,
+		bson.M{"blockedBy": bson.M{"$in": objIDs}},
+		bson.D{{Key: "$pull", Value: bson.M{"blockedBy": bson.M{"$in": objIDs}}}, bumpVersion},
+	)
+	return err
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// exportBatchSize is how many tasks each getMore fetches; it bounds the
+// memory an export holds at once.
+const exportBatchSize = 500
+
+func (r *MongoTaskRepository) Export(ctx context.Context, filter service.TaskFilter) (service.TaskCursor, error) {
+	pipeline, err := r.filterPipeline(filter)
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}}})
+
+	// No per-operation timeout: an export runs for as long as the client
+	// keeps reading.
+	cur, err := r.collection.Aggregate(ctx, pipeline, options.Aggregate().SetBatchSize(exportBatchSize).SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	return &taskCursor{cur: cur}, nil
+}
+
+type taskCursor struct {
+	cur  *mongo.Cursor
+	task service.Task
+	err  error
+}
+
+func (c *taskCursor) Next(ctx context.Context) bool {
+	if c.err != nil || !c.cur.Next(ctx) {
+		return false
+	}
+	var doc taskDocument
+	if err := c.cur.Decode(&doc); err != nil {
+		c.err = err
+		return false
+	}
+	c.task = toTask(doc)
+	return true
+}
+
+func (c *taskCursor) Task() service.Task {
+	return c.task
+}
+
+func (c *taskCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.cur.Err()
+}
+
+func (c *taskCursor) Close(ctx context.Context) error {
+	return c.cur.Close(ctx)
+}
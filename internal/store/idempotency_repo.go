@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	idempotencyTTLIndexName = "createdAt_ttl"
+	// pendingLockTimeout is how long a reserved key may stay without a
+	// response before another request may take it over, so a crashed
+	// request does not block its key until the record expires.
+	pendingLockTimeout = time.Minute
+)
+
+type MongoIdempotencyRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+type idempotencyDocument struct {
+	Key         string        `bson:"_id"`
+	RequestHash string        `bson:"requestHash"`
+	Owner       string        `bson:"owner"`
+	Task        *service.Task `bson:"task,omitempty"`
+	CreatedAt   time.Time     `bson:"createdAt"`
+}
+
+func NewMongoIdempotencyRepository(store *MongoStore, collectionName string) *MongoIdempotencyRepository {
+	return &MongoIdempotencyRepository{
+		collection: store.db.Collection(collectionName),
+		timeout:    store.timeout,
+	}
+}
+
+// EnsureIndexes creates the TTL index that expires records ttl after the
+// first request.
+func (r *MongoIdempotencyRepository) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return ensureTTLIndex(opCtx, r.collection, idempotencyTTLIndexName, "createdAt", ttl)
+}
+
+// Reserve relies on the unique _id: of two concurrent requests with the
+// same key only one insert succeeds.
+func (r *MongoIdempotencyRepository) Reserve(ctx context.Context, record service.IdempotencyRecord) (*service.IdempotencyRecord, bool, error) {
+	doc := idempotencyDocument{
+		Key:         record.Key,
+		RequestHash: record.RequestHash,
+		Owner:       record.Owner,
+		CreatedAt:   record.CreatedAt,
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.InsertOne(opCtx, doc)
+	if err == nil {
+		return &record, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+
+	// Take over a reservation whose request never completed.
+	res, err := r.collection.ReplaceOne(opCtx, bson.M{
+		"_id":       record.Key,
+		"task":      bson.M{"$exists": false},
+		"createdAt": bson.M{"$lt": record.CreatedAt.Add(-pendingLockTimeout)},
+	}, doc)
+	if err != nil {
+		return nil, false, err
+	}
+	if res.ModifiedCount == 1 {
+		return &record, true, nil
+	}
+
+	var existing idempotencyDocument
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": record.Key}).Decode(&existing); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The record expired in the meantime; let the client retry.
+			return nil, false, &service.ConflictError{Message: "a request with this Idempotency-Key is still in progress"}
+		}
+		return nil, false, err
+	}
+	return &service.IdempotencyRecord{
+		Key:         existing.Key,
+		RequestHash: existing.RequestHash,
+		Owner:       existing.Owner,
+		Task:        existing.Task,
+		CreatedAt:   existing.CreatedAt,
+	}, false, nil
+}
+
+// Complete and Release match the owner as well as the key, so a request
+// whose reservation was taken over leaves the new holder's alone.
+func (r *MongoIdempotencyRepository) Complete(ctx context.Context, key, owner string, task service.Task) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.collection.UpdateOne(opCtx,
+		bson.M{"_id": key, "owner": owner},
+		bson.M{"$set": bson.M{"task": task}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return service.ErrIdempotencyKeyTakenOver
+	}
+	return nil
+}
+
+func (r *MongoIdempotencyRepository) Release(ctx context.Context, key, owner string) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.DeleteOne(opCtx, bson.M{"_id": key, "owner": owner, "task": bson.M{"$exists": false}})
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -54,3 +55,46 @@ func (s *MongoStore) Disconnect(ctx context.Context) error {
 func (s *MongoStore) Collection() *mongo.Collection {
 	return s.collection
 }
+
+// ensureTTLIndex maintains a TTL index named name that expires documents
+// ttl after the time in field. A zero ttl drops the index.
+func ensureTTLIndex(ctx context.Context, collection *mongo.Collection, name, field string, ttl time.Duration) error {
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	var existing *mongo.IndexSpecification
+	for _, spec := range specs {
+		if spec.Name == name {
+			existing = spec
+			break
+		}
+	}
+
+	seconds := int32(ttl / time.Second)
+	switch {
+	case ttl <= 0:
+		if existing == nil {
+			return nil
+		}
+		_, err := collection.Indexes().DropOne(ctx, name)
+		return err
+	case existing == nil:
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: field, Value: 1}},
+			Options: options.Index().SetName(name).SetExpireAfterSeconds(seconds),
+		})
+		return err
+	case existing.ExpireAfterSeconds == nil || *existing.ExpireAfterSeconds != seconds:
+		// collMod changes the expiry in place instead of rebuilding the index.
+		return collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection.Name()},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: name},
+				{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// Patch translates each operation into a stage of a pipeline update, so the
+// patch is applied server-side in one write instead of replacing the task.
+// Single tags are inserted, replaced and removed by slicing the array around
+// their index.
+func (r *MongoTaskRepository) Patch(ctx context.Context, id string, ops []service.PatchOperation, version int64) (*service.Task, error) {
+	objID, err := parseObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	update := mongo.Pipeline{}
+	for _, op := range ops {
+		stage, err := patchStage(op)
+		if err != nil {
+			return nil, err
+		}
+		update = append(update, stage)
+		if done, ok := op.Value.(bool); ok {
+			update = append(update, completedAtStage(done, now))
+		}
+	}
+	update = append(update, bson.D{{Key: "$set", Value: bson.M{"version": bumpVersionExpr}}})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc taskDocument
+	if err := r.collection.FindOneAndUpdate(opCtx, withVersion(activeByID(objID), &version), update, opts).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, r.versionMiss(ctx, id, &version)
+		}
+		return nil, err
+	}
+	r.recordChange(ctx)
+
+	task := toTask(doc)
+	return &task, nil
+}
+
+func patchStage(op service.PatchOperation) (bson.D, error) {
+	field, index := service.ParsePatchPath(op.Path)
+	if index != nil {
+		return bson.D{{Key: "$set", Value: bson.M{"tags": tagsExpr(op, *index)}}}, nil
+	}
+	if op.Op == service.PatchRemove {
+		return bson.D{{Key: "$unset", Value: field}}, nil
+	}
+
+	var value any
+	switch v := op.Value.(type) {
+	case service.Priority:
+		value = string(v)
+	case time.Time, bool, []string:
+		value = v
+	case string:
+		value = v
+		if field == "projectId" {
+			if v == "" {
+				return bson.D{{Key: "$unset", Value: field}}, nil
+			}
+			projectID, err := parseProjectID(v)
+			if err != nil {
+				return nil, err
+			}
+			value = projectID
+		}
+	default:
+		return nil, &service.ValidationError{Field: "value", Message: "unsupported value", Value: op.Value}
+	}
+	return bson.D{{Key: "$set", Value: bson.M{field: bson.M{"$literal": value}}}}, nil
+}
+
+// completedAtStage keeps completedAt in step with done, as taskUpdate does.
+func completedAtStage(done bool, now time.Time) bson.D {
+	if !done {
+		return bson.D{{Key: "$unset", Value: "completedAt"}}
+	}
+	return bson.D{{Key: "$set", Value: bson.M{"completedAt": bson.M{"$ifNull": bson.A{"$completedAt", now}}}}}
+}
+
+// tagsExpr rebuilds the tags array around index: add inserts before it (or
+// appends for service.PatchTagsAppend), replace swaps it and remove drops it.
+func tagsExpr(op service.PatchOperation, index int) bson.M {
+	tags := bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}
+	if index == service.PatchTagsAppend {
+		return bson.M{"$concatArrays": bson.A{tags, bson.A{bson.M{"$literal": op.Value}}}}
+	}
+
+	head := any(bson.A{})
+	if index > 0 {
+		head = bson.M{"$slice": bson.A{tags, 0, index}}
+	}
+	from := index
+	if op.Op != service.PatchAdd {
+		from++
+	}
+	// $slice needs a positive count; anything past the end is ignored.
+	tail := bson.M{"$slice": bson.A{tags, from, bson.M{"$max": bson.A{bson.M{"$size": tags}, 1}}}}
+
+	parts := bson.A{head}
+	if op.Op != service.PatchRemove {
+		parts = append(parts, bson.A{bson.M{"$literal": op.Value}})
+	}
+	return bson.M{"$concatArrays": append(parts, tail)}
+}
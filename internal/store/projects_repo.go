@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const projectNameIndexName = "name_unique"
+
+type MongoProjectRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+type projectDocument struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Name        string             `bson:"name"`
+	Description string             `bson:"description,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"`
+}
+
+func NewMongoProjectRepository(store *MongoStore, collectionName string) *MongoProjectRepository {
+	return &MongoProjectRepository{
+		collection: store.db.Collection(collectionName),
+		timeout:    store.timeout,
+	}
+}
+
+func (r *MongoProjectRepository) EnsureIndexes(ctx context.Context) error {
+	idx := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetName(projectNameIndexName).SetUnique(true),
+	}
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.Indexes().CreateOne(opCtx, idx)
+	return err
+}
+
+func (r *MongoProjectRepository) Create(ctx context.Context, project service.Project) (*service.Project, error) {
+	doc := projectDocument{
+		ID:          primitive.NewObjectID(),
+		Name:        project.Name,
+		Description: project.Description,
+		CreatedAt:   project.CreatedAt,
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.collection.InsertOne(opCtx, doc); err != nil {
+		return nil, projectWriteError(err, project.Name)
+	}
+
+	project.ID = doc.ID.Hex()
+	return &project, nil
+}
+
+func (r *MongoProjectRepository) Get(ctx context.Context, id string) (*service.Project, error) {
+	objID, err := parseProjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc projectDocument
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": objID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	project := toProject(doc)
+	return &project, nil
+}
+
+func (r *MongoProjectRepository) List(ctx context.Context) ([]service.Project, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	projects := []service.Project{}
+	for cur.Next(opCtx) {
+		var doc projectDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		projects = append(projects, toProject(doc))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *MongoProjectRepository) Update(ctx context.Context, id string, update service.UpdateProjectRequest) (*service.Project, error) {
+	objID, err := parseProjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.D{}
+	if update.Name != nil {
+		set = append(set, bson.E{Key: "name", Value: *update.Name})
+	}
+	if update.Description != nil {
+		set = append(set, bson.E{Key: "description", Value: *update.Description})
+	}
+	if len(set) == 0 {
+		return nil, &service.ValidationError{Field: "body", Message: "at least one field must be provided"}
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc projectDocument
+	if err := r.collection.FindOneAndUpdate(
+		opCtx,
+		bson.M{"_id": objID},
+		bson.D{{Key: "$set", Value: set}},
+		opts,
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrProjectNotFound
+		}
+		name := ""
+		if update.Name != nil {
+			name = *update.Name
+		}
+		return nil, projectWriteError(err, name)
+	}
+
+	project := toProject(doc)
+	return &project, nil
+}
+
+func (r *MongoProjectRepository) Delete(ctx context.Context, id string) error {
+	objID, err := parseProjectID(id)
+	if err != nil {
+		return err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.collection.DeleteOne(opCtx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return service.ErrProjectNotFound
+	}
+	return nil
+}
+
+func projectWriteError(err error, name string) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return &service.ConflictError{Message: fmt.Sprintf("a project named %q already exists", name)}
+	}
+	return err
+}
+
+func parseProjectID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %s", service.ErrInvalidProjectID, id)
+	}
+	return objID, nil
+}
+
+func toProject(doc projectDocument) service.Project {
+	return service.Project{
+		ID:          doc.ID.Hex(),
+		Name:        doc.Name,
+		Description: doc.Description,
+		CreatedAt:   doc.CreatedAt,
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const revisionIndexName = "taskId_rev_unique"
+
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+type revisionDocument struct {
+	ID        primitive.ObjectID    `bson:"_id,omitempty"`
+	TaskID    primitive.ObjectID    `bson:"taskId"`
+	Rev       int                   `bson:"rev"`
+	Actor     string                `bson:"actor,omitempty"`
+	Changes   []service.FieldChange `bson:"changes"`
+	CreatedAt time.Time             `bson:"createdAt"`
+	Task      *service.Task         `bson:"task,omitempty"`
+}
+
+func NewMongoRevisionRepository(store *MongoStore, collectionName string) *MongoRevisionRepository {
+	return &MongoRevisionRepository{
+		collection: store.db.Collection(collectionName),
+		timeout:    store.timeout,
+	}
+}
+
+func (r *MongoRevisionRepository) EnsureIndexes(ctx context.Context) error {
+	idx := mongo.IndexModel{
+		Keys:    bson.D{{Key: "taskId", Value: 1}, {Key: "rev", Value: -1}},
+		Options: options.Index().SetName(revisionIndexName).SetUnique(true),
+	}
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.Indexes().CreateOne(opCtx, idx)
+	return err
+}
+
+// Append relies on the unique (taskId, rev) index: the revision number is
+// the task version, which only one update can produce.
+func (r *MongoRevisionRepository) Append(ctx context.Context, revision service.Revision) (*service.Revision, error) {
+	taskID, err := parseObjectID(revision.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	doc := revisionDocument{
+		ID:        primitive.NewObjectID(),
+		TaskID:    taskID,
+		Rev:       revision.Rev,
+		Actor:     revision.Actor,
+		Changes:   revision.Changes,
+		CreatedAt: revision.CreatedAt,
+		Task:      revision.Task,
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.collection.InsertOne(opCtx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("revision %d of task %s already recorded", revision.Rev, revision.TaskID)
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (r *MongoRevisionRepository) ListRevisions(ctx context.Context, taskID string, before, limit int) ([]service.Revision, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.D{{Key: "taskId", Value: objID}}
+	if before > 0 {
+		query = append(query, bson.E{Key: "rev", Value: bson.M{"$lt": before}})
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "rev", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"task": 0})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	revisions := []service.Revision{}
+	for cur.Next(opCtx) {
+		var doc revisionDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, toRevision(doc))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *MongoRevisionRepository) GetRevision(ctx context.Context, taskID string, rev int) (*service.Revision, error) {
+	objID, err := parseObjectID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc revisionDocument
+	if err := r.collection.FindOne(opCtx, bson.M{"taskId": objID, "rev": rev}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	revision := toRevision(doc)
+	return &revision, nil
+}
+
+func toRevision(doc revisionDocument) service.Revision {
+	task := doc.Task
+	if task != nil {
+		task.ChecklistProgress = service.NewChecklistProgress(task.Checklist)
+	}
+	return service.Revision{
+		TaskID:    doc.TaskID.Hex(),
+		Rev:       doc.Rev,
+		Actor:     doc.Actor,
+		Changes:   doc.Changes,
+		CreatedAt: doc.CreatedAt,
+		Task:      task,
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	textIndexName = "title_tags_text"
+	// tagMatchBoost is added to the text score for every search term that
+	// equals one of the task's tags exactly.
+	tagMatchBoost = 2.0
+)
+
+// textIndexWeights and textIndexLanguage define the text index; changing
+// them makes the next startup rebuild it.
+var textIndexWeights = map[string]int{"title": 3, "tags": 1}
+
+const textIndexLanguage = "none"
+
+func textIndexModel() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}, {Key: "tags", Value: "text"}},
+		Options: options.Index().
+			SetName(textIndexName).
+			SetWeights(bson.D{{Key: "title", Value: textIndexWeights["title"]}, {Key: "tags", Value: textIndexWeights["tags"]}}).
+			SetDefaultLanguage(textIndexLanguage),
+	}
+}
+
+// dropStaleTextIndexes removes our text index when its definition no
+// longer matches textIndexModel, since MongoDB would otherwise refuse to
+// create the new one. Text indexes under other names are left alone: they
+// may have been created on purpose, and index creation then reports the
+// clash instead.
+func (r *MongoTaskRepository) dropStaleTextIndexes(ctx context.Context) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Indexes().List(opCtx)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(opCtx)
+	for cur.Next(opCtx) {
+		var spec struct {
+			Name            string         `bson:"name"`
+			Weights         map[string]int `bson:"weights"`
+			DefaultLanguage string         `bson:"default_language"`
+		}
+		if err := cur.Decode(&spec); err != nil {
+			return err
+		}
+		if spec.Name != textIndexName {
+			continue
+		}
+		if maps.Equal(spec.Weights, textIndexWeights) && spec.DefaultLanguage == textIndexLanguage {
+			return nil
+		}
+		_, err := r.collection.Indexes().DropOne(opCtx, spec.Name)
+		return err
+	}
+	return cur.Err()
+}
+
+// searchScoreStage computes the relevance score of a $text match, boosted
+// for search terms that exactly match a tag.
+func searchScoreStage(q string) bson.D {
+	terms := bson.A{}
+	for _, term := range strings.Fields(q) {
+		terms = append(terms, term)
+	}
+	return bson.D{{Key: "$addFields", Value: bson.M{
+		"score": bson.M{"$add": bson.A{
+			bson.M{"$meta": "textScore"},
+			bson.M{"$multiply": bson.A{
+				tagMatchBoost,
+				bson.M{"$size": bson.M{"$setIntersection": bson.A{
+					bson.M{"$ifNull": bson.A{"$tags", bson.A{}}},
+					terms,
+				}}},
+			}},
+		}},
+	}}}
+}
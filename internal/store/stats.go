@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const oldestAgeBucket = "older"
+
+type statsDocument struct {
+	Totals []struct {
+		Total int64 `bson:"total"`
+		Done  int64 `bson:"done"`
+	} `bson:"totals"`
+	Tags    []service.TagStats `bson:"tags"`
+	OpenAge []struct {
+		Bound any   `bson:"_id"`
+		Count int64 `bson:"count"`
+	} `bson:"openAge"`
+}
+
+// Stats computes every figure in a single aggregation: the filter stages
+// are shared and $facet runs the three breakdowns over their output.
+func (r *MongoTaskRepository) Stats(ctx context.Context, filter service.TaskFilter, now time.Time) (*service.TaskStats, error) {
+	pipeline, err := r.filterPipeline(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	doneCount := bson.M{"$sum": bson.M{"$cond": bson.A{"$done", 1, 0}}}
+	// Ages are in milliseconds; tasks created after now count as new.
+	bounds := bson.A{int64(0)}
+	for _, bound := range service.OpenAgeBounds {
+		bounds = append(bounds, bound.Milliseconds())
+	}
+	age := bson.M{"$max": bson.A{int64(0), bson.M{"$subtract": bson.A{now, "$createdAt"}}}}
+
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"totals": bson.A{
+			bson.M{"$group": bson.M{"_id": nil, "total": bson.M{"$sum": 1}, "done": doneCount}},
+		},
+		"tags": bson.A{
+			bson.M{"$unwind": "$tags"},
+			bson.M{"$group": bson.M{"_id": "$tags", "total": bson.M{"$sum": 1}, "done": doneCount}},
+			bson.M{"$set": bson.M{"open": bson.M{"$subtract": bson.A{"$total", "$done"}}}},
+			bson.M{"$sort": bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}},
+		},
+		"openAge": bson.A{
+			bson.M{"$match": bson.M{"done": false}},
+			bson.M{"$bucket": bson.M{
+				"groupBy":    age,
+				"boundaries": bounds,
+				"default":    oldestAgeBucket,
+				"output":     bson.M{"count": bson.M{"$sum": 1}},
+			}},
+		},
+	}}})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	var doc statsDocument
+	if cur.Next(opCtx) {
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := &service.TaskStats{
+		Tags:    doc.Tags,
+		OpenAge: make([]service.AgeBucket, len(bounds)),
+	}
+	if stats.Tags == nil {
+		stats.Tags = []service.TagStats{}
+	}
+	if len(doc.Totals) > 0 {
+		stats.Total = doc.Totals[0].Total
+		stats.Done = doc.Totals[0].Done
+		stats.Open = stats.Total - stats.Done
+	}
+	for _, bucket := range doc.OpenAge {
+		index := len(bounds) - 1
+		if bound, ok := bucket.Bound.(int64); ok {
+			for i, b := range bounds {
+				if b == bound {
+					index = i
+				}
+			}
+		}
+		stats.OpenAge[index].Count = bucket.Count
+	}
+	return stats, nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+func (r *MongoTaskRepository) ListTags(ctx context.Context) ([]service.TagCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"deletedAt": bson.M{"$exists": false}, "tags.0": bson.M{"$exists": true}}}},
+		{{Key: "$project", Value: bson.M{"tags": 1, "done": 1}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$tags",
+			"count": bson.M{"$sum": 1},
+			"open":  bson.M{"$sum": bson.M{"$cond": bson.A{"$done", 0, 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	tags := []service.TagCount{}
+	if err := cur.All(opCtx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ReplaceTags rewrites sources to target on the tasks matching filter in
+// three passes, each safe to repeat: tasks that already have target drop
+// the sources, the rest get the sources renamed in place through an array
+// filter, and tasks that had several sources are deduplicated. The passes
+// push a write token, see batchMisses, by which the tasks they changed are
+// found afterwards; their previous tags come from a read made just before.
+func (r *MongoTaskRepository) ReplaceTags(ctx context.Context, filter service.TaskFilter, sources []string, target string) ([]service.TagReplacement, error) {
+	query, err := taskQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Blocked != nil {
+		ids, err := r.matchingIDs(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		query = append(query, bson.E{Key: "_id", Value: bson.M{"$in": ids}})
+	}
+	// The filter may match on tags itself, so the conditions of each pass
+	// are combined with it rather than added to it.
+	matching := func(cond bson.M) bson.M {
+		return bson.M{"$and": bson.A{query, cond}}
+	}
+	from := bson.A{}
+	for _, tag := range sources {
+		from = append(from, tag)
+	}
+	token := primitive.NewObjectID()
+	pushToken := bson.E{Key: "$push", Value: bson.M{"writeTokens": bson.M{"$each": bson.A{token}, "$slice": -maxWriteTokens}}}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	type taskTags struct {
+		ID   primitive.ObjectID `bson:"_id"`
+		Tags []string           `bson:"tags"`
+	}
+	cur, err := r.collection.Find(opCtx, matching(bson.M{"tags": bson.M{"$in": from}}),
+		options.Find().SetProjection(bson.M{"tags": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var before []taskTags
+	if err := cur.All(opCtx, &before); err != nil {
+		return nil, err
+	}
+	if len(before) == 0 {
+		return nil, nil
+	}
+
+	if _, err := r.collection.UpdateMany(opCtx,
+		matching(bson.M{"tags": bson.M{"$all": bson.A{target}, "$in": from}}),
+		bson.D{{Key: "$pull", Value: bson.M{"tags": bson.M{"$in": from}}}, pushToken, bumpVersion},
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.collection.UpdateMany(opCtx,
+		matching(bson.M{"tags": bson.M{"$in": from}}),
+		bson.D{{Key: "$set", Value: bson.M{"tags.$[tag]": target}}, pushToken, bumpVersion},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []any{bson.M{"tag": bson.M{"$in": from}}}}),
+	); err != nil {
+		return nil, err
+	}
+
+	if len(sources) > 1 {
+		targetCount := bson.M{"$size": bson.M{"$filter": bson.M{
+			"input": "$tags",
+			"cond":  bson.M{"$eq": bson.A{"$$this", target}},
+		}}}
+		dedupe := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"tags": bson.M{"$reduce": bson.M{
+				"input":        "$tags",
+				"initialValue": bson.A{},
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$in": bson.A{"$$this", "$$value"}},
+					"$$value",
+					bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+				}},
+			}},
+			"version": bumpVersionExpr,
+		}}}}
+		if _, err := r.collection.UpdateMany(opCtx,
+			matching(bson.M{"tags": target, "$expr": bson.M{"$gt": bson.A{targetCount, 1}}}),
+			dedupe,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	// Every task changed now has target, which narrows the lookup to the
+	// tags index.
+	cur, err = r.collection.Find(opCtx, bson.M{"tags": target, "writeTokens": token},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var changed []taskTags
+	if err := cur.All(opCtx, &changed); err != nil {
+		return nil, err
+	}
+	if len(changed) > 0 {
+		r.recordChange(ctx)
+	}
+
+	previous := make(map[primitive.ObjectID][]string, len(before))
+	for _, task := range before {
+		previous[task.ID] = task.Tags
+	}
+	replaced := make([]service.TagReplacement, len(changed))
+	for i, task := range changed {
+		replaced[i] = service.TagReplacement{ID: task.ID.Hex(), Tags: previous[task.ID]}
+	}
+	return replaced, nil
+}
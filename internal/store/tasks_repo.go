@@ -15,35 +15,60 @@ import (
 	"task-api-huma-mongo/internal/service"
 )
 
+const (
+	createdAtIndexName   = "createdAt_id"
+	dueAtIndexName       = "dueAt"
+	completedAtIndexName = "completedAt"
+	blockedByIndexName   = "blockedBy"
+	projectIDIndexName   = "projectId"
+	tagsIndexName        = "tags"
+	uidIndexName         = "uid_unique"
+	trashTTLIndexName    = "deletedAt_ttl"
+	writeBatchSize       = 1000
+)
+
 type MongoTaskRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
-	timeout    time.Duration
+	// counters holds the change counter of the collection, see
+	// recordChange.
+	counters *mongo.Collection
+	timeout  time.Duration
 }
 
 type taskDocument struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Title     string             `bson:"title"`
-	Done      bool               `bson:"done"`
-	Tags      []string           `bson:"tags,omitempty"`
-	CreatedAt time.Time          `bson:"createdAt"`
+	ID          primitive.ObjectID      `bson:"_id,omitempty"`
+	Title       string                  `bson:"title"`
+	Description string                  `bson:"description,omitempty"`
+	Done        bool                    `bson:"done"`
+	Priority    string                  `bson:"priority,omitempty"`
+	ProjectID   primitive.ObjectID      `bson:"projectId,omitempty"`
+	Tags        []string                `bson:"tags,omitempty"`
+	Checklist   []checklistItemDocument `bson:"checklist,omitempty"`
+	BlockedBy   []primitive.ObjectID    `bson:"blockedBy,omitempty"`
+	CreatedAt   time.Time               `bson:"createdAt"`
+	CompletedAt *time.Time              `bson:"completedAt,omitempty"`
+	DueAt       *time.Time              `bson:"dueAt,omitempty"`
+	RemindAt    *time.Time              `bson:"remindAt,omitempty"`
+	UID         string                  `bson:"uid,omitempty"`
+	DeletedAt   *time.Time              `bson:"deletedAt,omitempty"`
+	Version     int64                   `bson:"version"`
+	Score       float64                 `bson:"score,omitempty"`
 }
 
-func NewMongoTaskRepository(store *MongoStore) *MongoTaskRepository {
+func NewMongoTaskRepository(store *MongoStore, countersCollection string) *MongoTaskRepository {
 	return &MongoTaskRepository{
 		client:     store.client,
 		collection: store.collection,
+		counters:   store.db.Collection(countersCollection),
 		timeout:    store.timeout,
 	}
 }
 
 func (r *MongoTaskRepository) Create(ctx context.Context, task service.Task) (*service.Task, error) {
-	doc := taskDocument{
-		ID:        primitive.NewObjectID(),
-		Title:     task.Title,
-		Done:      task.Done,
-		Tags:      task.Tags,
-		CreatedAt: task.CreatedAt,
+	doc, err := newTaskDocument(task)
+	if err != nil {
+		return nil, err
 	}
 
 	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
@@ -51,8 +76,10 @@ func (r *MongoTaskRepository) Create(ctx context.Context, task service.Task) (*s
 	if _, err := r.collection.InsertOne(opCtx, doc); err != nil {
 		return nil, err
 	}
+	r.recordChange(ctx)
 
 	task.ID = doc.ID.Hex()
+	task.Version = doc.Version
 	return &task, nil
 }
 
@@ -65,7 +92,7 @@ func (r *MongoTaskRepository) Get(ctx context.Context, id string) (*service.Task
 	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 	var doc taskDocument
-	if err := r.collection.FindOne(opCtx, bson.M{"_id": objID}).Decode(&doc); err != nil {
+	if err := r.collection.FindOne(opCtx, activeByID(objID)).Decode(&doc); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, service.ErrNotFound
 		}
@@ -76,35 +103,64 @@ func (r *MongoTaskRepository) Get(ctx context.Context, id string) (*service.Task
 	return &task, nil
 }
 
-func (r *MongoTaskRepository) List(ctx context.Context, filter service.TaskFilter) ([]service.Task, error) {
-	query := bson.D{}
-	if filter.Done != nil {
-		query = append(query, bson.E{Key: "done", Value: *filter.Done})
+func (r *MongoTaskRepository) List(ctx context.Context, filter service.TaskFilter, opts service.ListOptions) (*service.TaskPage, error) {
+	search := filter.Query != ""
+	spec, err := parseSort(opts.Sort, search)
+	if err != nil {
+		return nil, err
 	}
-	if filter.Tag != "" {
-		query = append(query, bson.E{Key: "tags", Value: filter.Tag})
+
+	pipeline, err := r.filterPipeline(filter)
+	if err != nil {
+		return nil, err
+	}
+	if search {
+		pipeline = append(pipeline, searchScoreStage(filter.Query))
+	}
+	if stage := spec.computedStage(); stage != nil {
+		pipeline = append(pipeline, stage)
 	}
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(spec, opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{spec.after(cursor)}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: spec.mongoSort()}},
+		bson.D{{Key: "$limit", Value: int64(opts.Limit) + 1}},
+	)
 
 	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	cur, err := r.collection.Find(opCtx, query)
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cur.Close(opCtx)
 
-	var tasks []service.Task
+	docs := make([]taskDocument, 0, opts.Limit+1)
 	for cur.Next(opCtx) {
 		var doc taskDocument
 		if err := cur.Decode(&doc); err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, toTask(doc))
+		docs = append(docs, doc)
 	}
 	if err := cur.Err(); err != nil {
 		return nil, err
 	}
-	return tasks, nil
+
+	page := &service.TaskPage{Items: make([]service.Task, 0, len(docs))}
+	if len(docs) > opts.Limit {
+		docs = docs[:opts.Limit]
+		page.NextCursor = encodeCursor(spec, docs[len(docs)-1])
+	}
+	for _, doc := range docs {
+		page.Items = append(page.Items, toTask(doc))
+	}
+	return page, nil
 }
 
 func (r *MongoTaskRepository) Update(ctx context.Context, id string, update service.UpdateTaskRequest) (*service.Task, error) {
@@ -113,18 +169,72 @@ func (r *MongoTaskRepository) Update(ctx context.Context, id string, update serv
 		return nil, err
 	}
 
-	set := bson.D{}
-	if update.Title != nil {
-		set = append(set, bson.E{Key: "title", Value: *update.Title})
+	updateDoc, err := taskUpdate(update, time.Now().UTC())
+	if err != nil {
+		return nil, err
 	}
-	if update.Done != nil {
-		set = append(set, bson.E{Key: "done", Value: *update.Done})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc taskDocument
+	if err := r.collection.FindOneAndUpdate(
+		opCtx,
+		withVersion(activeByID(objID), update.IfVersion),
+		updateDoc,
+		opts,
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, r.versionMiss(ctx, id, update.IfVersion)
+		}
+		return nil, err
 	}
-	if update.Tags != nil {
-		set = append(set, bson.E{Key: "tags", Value: *update.Tags})
+	r.recordChange(ctx)
+
+	task := toTask(doc)
+	return &task, nil
+}
+
+func (r *MongoTaskRepository) Delete(ctx context.Context, id string, ifVersion *int64) error {
+	objID, err := parseObjectID(id)
+	if err != nil {
+		return err
 	}
-	if len(set) == 0 {
-		return nil, &service.ValidationError{Field: "body", Message: "at least one field must be provided"}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.collection.UpdateOne(opCtx,
+		withVersion(activeByID(objID), ifVersion),
+		bson.D{{Key: "$set", Value: bson.M{"deletedAt": time.Now().UTC()}}, bumpVersion},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return r.versionMiss(ctx, id, ifVersion)
+	}
+	r.recordChange(ctx)
+	return nil
+}
+
+// versionMiss explains why a write guarded by ifVersion matched nothing:
+// either the task does not exist or it is at another version.
+func (r *MongoTaskRepository) versionMiss(ctx context.Context, id string, ifVersion *int64) error {
+	if ifVersion == nil {
+		return service.ErrNotFound
+	}
+	current, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return &service.VersionMismatchError{Current: current.Version}
+}
+
+// Restore moves a task out of the trash.
+func (r *MongoTaskRepository) Restore(ctx context.Context, id string) (*service.Task, error) {
+	objID, err := parseObjectID(id)
+	if err != nil {
+		return nil, err
 	}
 
 	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
@@ -133,8 +243,8 @@ func (r *MongoTaskRepository) Update(ctx context.Context, id string, update serv
 	var doc taskDocument
 	if err := r.collection.FindOneAndUpdate(
 		opCtx,
-		bson.M{"_id": objID},
-		bson.D{{Key: "$set", Value: set}},
+		trashedByID(objID),
+		bson.D{{Key: "$unset", Value: bson.M{"deletedAt": ""}}, bumpVersion},
 		opts,
 	).Decode(&doc); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -142,12 +252,14 @@ func (r *MongoTaskRepository) Update(ctx context.Context, id string, update serv
 		}
 		return nil, err
 	}
+	r.recordChange(ctx)
 
 	task := toTask(doc)
 	return &task, nil
 }
 
-func (r *MongoTaskRepository) Delete(ctx context.Context, id string) error {
+// Purge permanently deletes a task that is in the trash.
+func (r *MongoTaskRepository) Purge(ctx context.Context, id string) error {
 	objID, err := parseObjectID(id)
 	if err != nil {
 		return err
@@ -155,14 +267,154 @@ func (r *MongoTaskRepository) Delete(ctx context.Context, id string) error {
 
 	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	res, err := r.collection.DeleteOne(opCtx, bson.M{"_id": objID})
+	res, err := r.collection.DeleteOne(opCtx, trashedByID(objID))
 	if err != nil {
 		return err
 	}
 	if res.DeletedCount == 0 {
 		return service.ErrNotFound
 	}
-	return nil
+	defer r.recordChange(ctx)
+	return r.detachBlockers(ctx, []primitive.ObjectID{objID})
+}
+
+// EmptyTrash permanently deletes every task in the trash. A task restored
+// while it runs is left alone, and is not among the IDs returned.
+func (r *MongoTaskRepository) EmptyTrash(ctx context.Context) ([]string, error) {
+	ids, err := r.matchingIDs(ctx, service.TaskFilter{Trashed: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []string
+	defer func() {
+		if len(purged) > 0 {
+			r.recordChange(ctx)
+		}
+	}()
+	for start := 0; start < len(ids); start += writeBatchSize {
+		batch := ids[start:min(start+writeBatchSize, len(ids))]
+
+		opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		res, err := r.collection.DeleteMany(opCtx, bson.M{"_id": bson.M{"$in": batch}, "deletedAt": bson.M{"$exists": true}})
+		cancel()
+		if err != nil {
+			return purged, err
+		}
+		deleted := batch
+		if res.DeletedCount < int64(len(batch)) {
+			if deleted, err = r.missingIDs(ctx, batch); err != nil {
+				return purged, err
+			}
+		}
+		for _, id := range deleted {
+			purged = append(purged, id.Hex())
+		}
+
+		if err := r.detachBlockers(ctx, deleted); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// missingIDs returns the IDs in ids that no task has.
+func (r *MongoTaskRepository) missingIDs(ctx context.Context, ids []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	found := map[primitive.ObjectID]bool{}
+	for cur.Next(opCtx) {
+		var doc taskDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		found[doc.ID] = true
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	var missing []primitive.ObjectID
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (r *MongoTaskRepository) Count(ctx context.Context, filter service.TaskFilter) (int64, error) {
+	pipeline, err := r.filterPipeline(filter)
+	if err != nil {
+		return 0, err
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$count", Value: "count"}})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(opCtx)
+
+	var result struct {
+		Count int64 `bson:"count"`
+	}
+	if cur.Next(opCtx) {
+		if err := cur.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+	return result.Count, cur.Err()
+}
+
+func (r *MongoTaskRepository) matchingIDs(ctx context.Context, filter service.TaskFilter) ([]primitive.ObjectID, error) {
+	pipeline, err := r.filterPipeline(filter)
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"_id": 1}}})
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	var ids []primitive.ObjectID
+	for cur.Next(opCtx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cur.Err()
+}
+
+// filterPipeline returns the aggregation stages selecting the tasks that
+// match filter, including the filters that need a $lookup.
+func (r *MongoTaskRepository) filterPipeline(filter service.TaskFilter) (mongo.Pipeline, error) {
+	query, err := taskQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query}}}
+	if filter.Blocked != nil {
+		pipeline = append(pipeline, r.blockedStages(*filter.Blocked)...)
+	}
+	return pipeline, nil
 }
 
 func (r *MongoTaskRepository) Ping(ctx context.Context) error {
@@ -171,6 +423,201 @@ func (r *MongoTaskRepository) Ping(ctx context.Context) error {
 	return r.client.Ping(opCtx, readpref.Primary())
 }
 
+// EnsureIndexes creates the indexes the repository queries rely on. It is
+// safe to call on every startup.
+func (r *MongoTaskRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.dropStaleTextIndexes(ctx); err != nil {
+		return err
+	}
+
+	models := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName(createdAtIndexName),
+		},
+		{
+			Keys:    bson.D{{Key: "projectId", Value: 1}},
+			Options: options.Index().SetName(projectIDIndexName).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "blockedBy", Value: 1}},
+			Options: options.Index().SetName(blockedByIndexName),
+		},
+		{
+			Keys:    bson.D{{Key: "dueAt", Value: 1}},
+			Options: options.Index().SetName(dueAtIndexName).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "completedAt", Value: 1}},
+			Options: options.Index().SetName(completedAtIndexName).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "tags", Value: 1}},
+			Options: options.Index().SetName(tagsIndexName),
+		},
+		{
+			Keys:    bson.D{{Key: "uid", Value: 1}},
+			Options: options.Index().SetName(uidIndexName).SetUnique(true).SetSparse(true),
+		},
+		textIndexModel(),
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.collection.Indexes().CreateMany(opCtx, models)
+	return err
+}
+
+// newTaskDocument builds the document inserted for a new task, with a
+// fresh ID.
+func newTaskDocument(task service.Task) (taskDocument, error) {
+	var projectID primitive.ObjectID
+	if task.ProjectID != "" {
+		parsed, err := parseProjectID(task.ProjectID)
+		if err != nil {
+			return taskDocument{}, err
+		}
+		projectID = parsed
+	}
+
+	return taskDocument{
+		ID:          primitive.NewObjectID(),
+		Title:       task.Title,
+		Description: task.Description,
+		Done:        task.Done,
+		Priority:    string(task.Priority),
+		ProjectID:   projectID,
+		Tags:        task.Tags,
+		CreatedAt:   task.CreatedAt,
+		CompletedAt: task.CompletedAt,
+		DueAt:       task.DueAt,
+		RemindAt:    task.RemindAt,
+		UID:         task.UID,
+		Version:     1,
+	}, nil
+}
+
+// taskUpdate builds the update document for the fields set in update. An
+// empty project ID removes the task from its project. Marking a task done
+// sets completedAt to now unless it is already set, which only happens
+// when the task was done before; reopening it clears completedAt.
+func taskUpdate(update service.UpdateTaskRequest, now time.Time) (bson.D, error) {
+	set := bson.D{}
+	unset := bson.D{}
+	completed := bson.D{}
+	if update.Title != nil {
+		set = append(set, bson.E{Key: "title", Value: *update.Title})
+	}
+	if update.Description != nil {
+		set = append(set, bson.E{Key: "description", Value: *update.Description})
+	}
+	if update.Done != nil {
+		set = append(set, bson.E{Key: "done", Value: *update.Done})
+		if *update.Done {
+			completed = append(completed, bson.E{Key: "completedAt", Value: now})
+		} else {
+			unset = append(unset, bson.E{Key: "completedAt", Value: ""})
+		}
+	}
+	if update.Priority != nil {
+		set = append(set, bson.E{Key: "priority", Value: string(*update.Priority)})
+	}
+	if update.ProjectID != nil {
+		if *update.ProjectID == "" {
+			unset = append(unset, bson.E{Key: "projectId", Value: ""})
+		} else {
+			projectID, err := parseProjectID(*update.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, bson.E{Key: "projectId", Value: projectID})
+		}
+	}
+	if update.Tags != nil {
+		set = append(set, bson.E{Key: "tags", Value: *update.Tags})
+	}
+	if update.DueAt != nil {
+		set = append(set, bson.E{Key: "dueAt", Value: *update.DueAt})
+	}
+	if update.RemindAt != nil {
+		set = append(set, bson.E{Key: "remindAt", Value: *update.RemindAt})
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return nil, &service.ValidationError{Field: "body", Message: "at least one field must be provided"}
+	}
+
+	doc := bson.D{}
+	if len(set) > 0 {
+		doc = append(doc, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		doc = append(doc, bson.E{Key: "$unset", Value: unset})
+	}
+	if len(completed) > 0 {
+		doc = append(doc, bson.E{Key: "$min", Value: completed})
+	}
+	return append(doc, bumpVersion), nil
+}
+
+func taskQuery(filter service.TaskFilter) (bson.D, error) {
+	query := bson.D{{Key: "deletedAt", Value: bson.M{"$exists": filter.Trashed}}}
+	if filter.ProjectID != "" {
+		projectID, err := parseProjectID(filter.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		query = append(query, bson.E{Key: "projectId", Value: projectID})
+	}
+	if filter.Done != nil {
+		query = append(query, bson.E{Key: "done", Value: *filter.Done})
+	}
+	if len(filter.Priorities) > 0 {
+		values := bson.A{}
+		for _, priority := range filter.Priorities {
+			values = append(values, string(priority))
+			if priority == service.PriorityNormal {
+				values = append(values, nil)
+			}
+		}
+		query = append(query, bson.E{Key: "priority", Value: bson.M{"$in": values}})
+	}
+	if filter.Tag != "" {
+		query = append(query, bson.E{Key: "tags", Value: filter.Tag})
+	}
+	if filter.Query != "" {
+		query = append(query, bson.E{Key: "$text", Value: bson.M{"$search": filter.Query}})
+	}
+	if filter.DueBefore != nil || filter.DueAfter != nil {
+		due := bson.M{}
+		if filter.DueBefore != nil {
+			due["$lt"] = *filter.DueBefore
+		}
+		if filter.DueAfter != nil {
+			due["$gt"] = *filter.DueAfter
+		}
+		query = append(query, bson.E{Key: "dueAt", Value: due})
+	}
+	if filter.CreatedBefore != nil || filter.CreatedAfter != nil {
+		created := bson.M{}
+		if filter.CreatedBefore != nil {
+			created["$lt"] = *filter.CreatedBefore
+		}
+		if filter.CreatedAfter != nil {
+			created["$gt"] = *filter.CreatedAfter
+		}
+		query = append(query, bson.E{Key: "createdAt", Value: created})
+	}
+	if filter.Overdue != nil {
+		overdue := bson.M{"done": false, "dueAt": bson.M{"$lt": time.Now().UTC()}}
+		if *filter.Overdue {
+			query = append(query, bson.E{Key: "$and", Value: bson.A{overdue}})
+		} else {
+			query = append(query, bson.E{Key: "$nor", Value: bson.A{overdue}})
+		}
+	}
+	return query, nil
+}
+
 func parseObjectID(id string) (primitive.ObjectID, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -179,12 +626,84 @@ func parseObjectID(id string) (primitive.ObjectID, error) {
 	return objID, nil
 }
 
+// activeByID matches a task by ID unless it is in the trash.
+func activeByID(objID primitive.ObjectID) bson.M {
+	return bson.M{"_id": objID, "deletedAt": bson.M{"$exists": false}}
+}
+
+func trashedByID(objID primitive.ObjectID) bson.M {
+	return bson.M{"_id": objID, "deletedAt": bson.M{"$exists": true}}
+}
+
+// bumpVersion is added to every update of a task document so its version,
+// and with it the ETag, changes with the content.
+var bumpVersion = bson.E{Key: "$inc", Value: bson.M{"version": 1}}
+
+// bumpVersionExpr is bumpVersion for pipeline updates. Documents written
+// before versions existed are at version 0.
+var bumpVersionExpr = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+
+// withVersion adds the optimistic concurrency check to filter, so the
+// comparison and the write happen atomically.
+func withVersion(filter bson.M, ifVersion *int64) bson.M {
+	if ifVersion == nil {
+		return filter
+	}
+	if *ifVersion == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		return filter
+	}
+	filter["version"] = *ifVersion
+	return filter
+}
+
+func hexID(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
+
+func hexIDs(ids []primitive.ObjectID) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.Hex())
+	}
+	return out
+}
+
+// toPriority maps the stored value to a priority, treating documents
+// written before priorities existed as normal.
+func toPriority(value string) service.Priority {
+	if value == "" {
+		return service.PriorityNormal
+	}
+	return service.Priority(value)
+}
+
 func toTask(doc taskDocument) service.Task {
+	checklist := toChecklist(doc.Checklist)
 	return service.Task{
-		ID:        doc.ID.Hex(),
-		Title:     doc.Title,
-		Done:      doc.Done,
-		Tags:      doc.Tags,
-		CreatedAt: doc.CreatedAt,
+		ID:                doc.ID.Hex(),
+		Title:             doc.Title,
+		Description:       doc.Description,
+		Done:              doc.Done,
+		Priority:          toPriority(doc.Priority),
+		ProjectID:         hexID(doc.ProjectID),
+		Tags:              doc.Tags,
+		Checklist:         checklist,
+		BlockedBy:         hexIDs(doc.BlockedBy),
+		ChecklistProgress: service.NewChecklistProgress(checklist),
+		CreatedAt:         doc.CreatedAt,
+		CompletedAt:       doc.CompletedAt,
+		DueAt:             doc.DueAt,
+		RemindAt:          doc.RemindAt,
+		UID:               doc.UID,
+		DeletedAt:         doc.DeletedAt,
+		Version:           doc.Version,
+		Score:             doc.Score,
 	}
 }
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// Timeseries buckets creations and completions with $dateTrunc in loc, one
+// facet each, and joins the two on the bucket start.
+func (r *MongoTaskRepository) Timeseries(ctx context.Context, bucket service.TimeBucket, from, to time.Time, loc *time.Location) ([]service.TimeseriesPoint, error) {
+	inRange := bson.M{"$gte": from, "$lt": to}
+	count := func(field, output string) bson.A {
+		return bson.A{
+			bson.M{"$match": bson.M{field: inRange}},
+			bson.M{"$group": bson.M{
+				"_id": bson.M{"$dateTrunc": bson.M{
+					"date":        "$" + field,
+					"unit":        string(bucket),
+					"timezone":    loc.String(),
+					"startOfWeek": "monday",
+				}},
+				output: bson.M{"$sum": 1},
+			}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"deletedAt": bson.M{"$exists": false},
+			"$or":       bson.A{bson.M{"createdAt": inRange}, bson.M{"completedAt": inRange}},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"created":   count("createdAt", "created"),
+			"completed": count("completedAt", "completed"),
+		}}},
+		{{Key: "$project", Value: bson.M{"points": bson.M{"$concatArrays": bson.A{"$created", "$completed"}}}}},
+		{{Key: "$unwind", Value: "$points"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$points._id",
+			"created":   bson.M{"$sum": "$points.created"},
+			"completed": bson.M{"$sum": "$points.completed"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Aggregate(opCtx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	points := []service.TimeseriesPoint{}
+	if err := cur.All(opCtx, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
@@ -0,0 +1,15 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// EnsureTrashRetention maintains the TTL index that permanently removes
+// tasks once they have been in the trash for longer than retention. A zero
+// retention keeps trashed tasks until they are purged explicitly.
+func (r *MongoTaskRepository) EnsureTrashRetention(ctx context.Context, retention time.Duration) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return ensureTTLIndex(opCtx, r.collection, trashTTLIndexName, "deletedAt", retention)
+}
@@ -0,0 +1,434 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	webhookActiveIndexName   = "active"
+	deliveryDueIndexName     = "status_nextAttemptAt"
+	deliveryWebhookIndexName = "webhookId_id"
+	deliveryTTLIndexName     = "finishedAt_ttl"
+)
+
+// MongoWebhookRepository keeps webhooks and their deliveries in two
+// collections.
+type MongoWebhookRepository struct {
+	collection *mongo.Collection
+	deliveries *mongo.Collection
+	timeout    time.Duration
+}
+
+type webhookDocument struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"`
+	URL       string              `bson:"url"`
+	Events    []service.EventType `bson:"events"`
+	Active    bool                `bson:"active"`
+	Secret    string              `bson:"secret"`
+	CreatedAt time.Time           `bson:"createdAt"`
+}
+
+type deliveryDocument struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty"`
+	WebhookID      primitive.ObjectID     `bson:"webhookId"`
+	Event          service.Event          `bson:"event"`
+	Status         service.DeliveryStatus `bson:"status"`
+	Attempts       int                    `bson:"attempts"`
+	NextAttemptAt  *time.Time             `bson:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time             `bson:"lastAttemptAt,omitempty"`
+	ResponseStatus int                    `bson:"responseStatus,omitempty"`
+	LastError      string                 `bson:"lastError,omitempty"`
+	RedeliveryOf   *primitive.ObjectID    `bson:"redeliveryOf,omitempty"`
+	CreatedAt      time.Time              `bson:"createdAt"`
+	// Lease is set by ClaimDelivery and cleared by RecordAttempt.
+	Lease string `bson:"lease,omitempty"`
+	// FinishedAt is set once the delivery succeeded or failed for good;
+	// the retention TTL index counts from it.
+	FinishedAt *time.Time `bson:"finishedAt,omitempty"`
+}
+
+func NewMongoWebhookRepository(store *MongoStore, collectionName, deliveriesCollectionName string) *MongoWebhookRepository {
+	return &MongoWebhookRepository{
+		collection: store.db.Collection(collectionName),
+		deliveries: store.db.Collection(deliveriesCollectionName),
+		timeout:    store.timeout,
+	}
+}
+
+func (r *MongoWebhookRepository) EnsureIndexes(ctx context.Context) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.collection.Indexes().CreateOne(opCtx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "active", Value: 1}},
+		Options: options.Index().SetName(webhookActiveIndexName),
+	}); err != nil {
+		return err
+	}
+	_, err := r.deliveries.Indexes().CreateMany(opCtx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+			Options: options.Index().SetName(deliveryDueIndexName),
+		},
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName(deliveryWebhookIndexName),
+		},
+	})
+	return err
+}
+
+// EnsureDeliveryRetention maintains the TTL index that removes deliveries
+// once they have been finished for longer than retention. Pending
+// deliveries are never removed. A zero retention keeps every delivery.
+func (r *MongoWebhookRepository) EnsureDeliveryRetention(ctx context.Context, retention time.Duration) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return ensureTTLIndex(opCtx, r.deliveries, deliveryTTLIndexName, "finishedAt", retention)
+}
+
+func (r *MongoWebhookRepository) Create(ctx context.Context, webhook service.Webhook) (*service.Webhook, error) {
+	doc := webhookDocument{
+		ID:        primitive.NewObjectID(),
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+		Secret:    webhook.Secret,
+		CreatedAt: webhook.CreatedAt,
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	if _, err := r.collection.InsertOne(opCtx, doc); err != nil {
+		return nil, err
+	}
+
+	webhook.ID = doc.ID.Hex()
+	return &webhook, nil
+}
+
+func (r *MongoWebhookRepository) Get(ctx context.Context, id string) (*service.Webhook, error) {
+	objID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc webhookDocument
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": objID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	webhook := toWebhook(doc)
+	return &webhook, nil
+}
+
+func (r *MongoWebhookRepository) List(ctx context.Context) ([]service.Webhook, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *MongoWebhookRepository) ListActive(ctx context.Context) ([]service.Webhook, error) {
+	return r.find(ctx, bson.M{"active": true})
+}
+
+func (r *MongoWebhookRepository) find(ctx context.Context, filter bson.M) ([]service.Webhook, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cur, err := r.collection.Find(opCtx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	webhooks := []service.Webhook{}
+	for cur.Next(opCtx) {
+		var doc webhookDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, toWebhook(doc))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *MongoWebhookRepository) Update(ctx context.Context, id string, update service.UpdateWebhookRequest) (*service.Webhook, error) {
+	objID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.D{}
+	if update.URL != nil {
+		set = append(set, bson.E{Key: "url", Value: *update.URL})
+	}
+	if update.Events != nil {
+		set = append(set, bson.E{Key: "events", Value: update.Events})
+	}
+	if update.Secret != nil {
+		set = append(set, bson.E{Key: "secret", Value: *update.Secret})
+	}
+	if update.Active != nil {
+		set = append(set, bson.E{Key: "active", Value: *update.Active})
+	}
+	if len(set) == 0 {
+		return nil, &service.ValidationError{Field: "body", Message: "at least one field must be provided"}
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc webhookDocument
+	if err := r.collection.FindOneAndUpdate(
+		opCtx,
+		bson.M{"_id": objID},
+		bson.D{{Key: "$set", Value: set}},
+		opts,
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	webhook := toWebhook(doc)
+	return &webhook, nil
+}
+
+func (r *MongoWebhookRepository) Delete(ctx context.Context, id string) error {
+	objID, err := parseWebhookID(id)
+	if err != nil {
+		return err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	res, err := r.collection.DeleteOne(opCtx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return service.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// CreateDeliveries sets the ID of every delivery it inserts.
+func (r *MongoWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []service.Delivery) error {
+	docs := make([]any, len(deliveries))
+	for i, delivery := range deliveries {
+		webhookID, err := parseWebhookID(delivery.WebhookID)
+		if err != nil {
+			return err
+		}
+		doc := deliveryDocument{
+			ID:            primitive.NewObjectID(),
+			WebhookID:     webhookID,
+			Event:         delivery.Event,
+			Status:        delivery.Status,
+			NextAttemptAt: delivery.NextAttemptAt,
+			CreatedAt:     delivery.CreatedAt,
+		}
+		if delivery.RedeliveryOf != "" {
+			original, err := parseDeliveryID(delivery.RedeliveryOf)
+			if err != nil {
+				return err
+			}
+			doc.RedeliveryOf = &original
+		}
+		docs[i] = doc
+		deliveries[i].ID = doc.ID.Hex()
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	_, err := r.deliveries.InsertMany(opCtx, docs)
+	return err
+}
+
+func (r *MongoWebhookRepository) GetDelivery(ctx context.Context, webhookID, id string) (*service.Delivery, error) {
+	webhookObjID, err := parseWebhookID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := parseDeliveryID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	var doc deliveryDocument
+	if err := r.deliveries.FindOne(opCtx, bson.M{"_id": objID, "webhookId": webhookObjID}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, service.ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	delivery := toDelivery(doc)
+	return &delivery, nil
+}
+
+func (r *MongoWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, status service.DeliveryStatus, limit int) ([]service.Delivery, error) {
+	objID, err := parseWebhookID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"webhookId": objID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cur, err := r.deliveries.Find(opCtx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(opCtx)
+
+	deliveries := []service.Delivery{}
+	for cur.Next(opCtx) {
+		var doc deliveryDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, toDelivery(doc))
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery leases the due delivery with a single findAndModify, so
+// that concurrent dispatchers never take the same one.
+func (r *MongoWebhookRepository) ClaimDelivery(ctx context.Context, now, leaseUntil time.Time, lease string) (*service.Delivery, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+	var doc deliveryDocument
+	if err := r.deliveries.FindOneAndUpdate(
+		opCtx,
+		bson.M{"status": service.DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"nextAttemptAt": leaseUntil, "lease": lease}},
+		opts,
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	delivery := toDelivery(doc)
+	return &delivery, nil
+}
+
+// RecordAttempt matches the lease along with the ID: once a lease expires
+// and the delivery is claimed again, the lease changes and the late
+// attempt is not recorded on top of the new one.
+func (r *MongoWebhookRepository) RecordAttempt(ctx context.Context, id, lease string, attempt service.DeliveryAttempt) error {
+	objID, err := parseDeliveryID(id)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"status": attempt.Status, "lastAttemptAt": attempt.At}
+	unset := bson.M{"lease": ""}
+	if attempt.NextAttemptAt != nil {
+		set["nextAttemptAt"] = *attempt.NextAttemptAt
+	} else {
+		unset["nextAttemptAt"] = ""
+	}
+	if attempt.Status != service.DeliveryPending {
+		set["finishedAt"] = attempt.At
+	}
+	if attempt.ResponseStatus != 0 {
+		set["responseStatus"] = attempt.ResponseStatus
+	} else {
+		unset["responseStatus"] = ""
+	}
+	if attempt.Error != "" {
+		set["lastError"] = attempt.Error
+	} else {
+		unset["lastError"] = ""
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	update := bson.M{"$set": set, "$unset": unset, "$inc": bson.M{"attempts": 1}}
+	res, err := r.deliveries.UpdateOne(opCtx, bson.M{"_id": objID, "lease": lease}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return service.ErrDeliveryLeaseLost
+	}
+	return nil
+}
+
+func parseWebhookID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %s", service.ErrInvalidWebhookID, id)
+	}
+	return objID, nil
+}
+
+func parseDeliveryID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %s", service.ErrInvalidDeliveryID, id)
+	}
+	return objID, nil
+}
+
+func toWebhook(doc webhookDocument) service.Webhook {
+	return service.Webhook{
+		ID:        doc.ID.Hex(),
+		URL:       doc.URL,
+		Events:    doc.Events,
+		Active:    doc.Active,
+		Secret:    doc.Secret,
+		CreatedAt: doc.CreatedAt,
+	}
+}
+
+func toDelivery(doc deliveryDocument) service.Delivery {
+	delivery := service.Delivery{
+		ID:             doc.ID.Hex(),
+		WebhookID:      doc.WebhookID.Hex(),
+		Event:          doc.Event,
+		Status:         doc.Status,
+		Attempts:       doc.Attempts,
+		NextAttemptAt:  doc.NextAttemptAt,
+		LastAttemptAt:  doc.LastAttemptAt,
+		ResponseStatus: doc.ResponseStatus,
+		LastError:      doc.LastError,
+		CreatedAt:      doc.CreatedAt,
+		Lease:          doc.Lease,
+	}
+	if doc.RedeliveryOf != nil {
+		delivery.RedeliveryOf = doc.RedeliveryOf.Hex()
+	}
+	return delivery
+}
@@ -0,0 +1,119 @@
+package todotxt
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+// DueKey is the key:value tag todo.txt tools use for due dates.
+const DueKey = "due"
+
+// priorities maps priorities onto todo.txt letters. Normal tasks get none,
+// which reads back as the default priority.
+var priorities = map[service.Priority]byte{
+	service.PriorityUrgent: 'A',
+	service.PriorityHigh:   'B',
+	service.PriorityLow:    'D',
+}
+
+// FromTask maps a task onto a todo.txt line. Tags starting with @ are
+// contexts, the others projects. A done task without completedAt counts as
+// completed when it was created, as imports assume. The format has no way
+// to escape a word, so title words that read as a project, context or
+// key:value tag, like +1 or @home, are imported back as one.
+func FromTask(task service.Task) Task {
+	created := task.CreatedAt.UTC()
+	line := Task{
+		Done:     task.Done,
+		Priority: priorities[task.Priority],
+		Created:  &created,
+		Text:     task.Title,
+	}
+	if task.Done {
+		completed := created
+		if task.CompletedAt != nil {
+			completed = task.CompletedAt.UTC()
+		}
+		line.Completed = &completed
+	}
+	for _, tag := range task.Tags {
+		if context, ok := strings.CutPrefix(tag, "@"); ok {
+			line.Contexts = append(line.Contexts, context)
+		} else {
+			line.Projects = append(line.Projects, tag)
+		}
+	}
+	if task.DueAt != nil {
+		line.Tags = map[string]string{DueKey: task.DueAt.UTC().Format(time.DateOnly)}
+	}
+	return line
+}
+
+// ImportTask maps the line onto a task to import. Contexts keep their @ so
+// that they are exported back as contexts, and key:value tags other than
+// due: stay in the title. A completed line without a creation date is
+// taken as created when it was completed. It fails with a ValidationError
+// when the due: tag is not a date.
+func (t Task) ImportTask() (service.ImportTask, error) {
+	done := t.Done
+	row := service.ImportTask{
+		CreateTaskRequest: service.CreateTaskRequest{
+			Title:    t.Text,
+			Done:     &done,
+			Priority: priority(t.Priority),
+		},
+		CreatedAt: t.Created,
+	}
+	if done {
+		row.CompletedAt = t.Completed
+		if row.CreatedAt == nil {
+			row.CreatedAt = t.Completed
+		}
+	}
+
+	tags := append([]string{}, t.Projects...)
+	for _, context := range t.Contexts {
+		tags = append(tags, "@"+context)
+	}
+	if len(tags) > 0 {
+		row.Tags = tags
+	}
+
+	var extra []string
+	for key, value := range t.Tags {
+		if key != DueKey {
+			extra = append(extra, key+":"+value)
+			continue
+		}
+		due, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return service.ImportTask{}, &service.ValidationError{Field: DueKey, Message: "must be a date in YYYY-MM-DD format", Value: value}
+		}
+		row.DueAt = &due
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		row.Title = strings.TrimSpace(row.Title + " " + strings.Join(extra, " "))
+	}
+	return row, nil
+}
+
+// priority maps a todo.txt letter onto the four priorities; no letter
+// leaves the default.
+func priority(letter byte) service.Priority {
+	switch {
+	case letter == 0:
+		return ""
+	case letter == 'A':
+		return service.PriorityUrgent
+	case letter == 'B':
+		return service.PriorityHigh
+	case letter == 'C':
+		return service.PriorityNormal
+	default:
+		return service.PriorityLow
+	}
+}
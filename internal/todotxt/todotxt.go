@@ -0,0 +1,196 @@
+// Package todotxt reads and writes tasks in the todo.txt format, one task
+// per line: https://github.com/todotxt/todo.txt.
+package todotxt
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const ContentType = "text/plain"
+
+const (
+	dateLayout = "2006-01-02"
+	// priorityKey holds the priority of a completed task, which the format
+	// drops from the start of the line.
+	priorityKey  = "pri"
+	maxLineBytes = 64 * 1024
+)
+
+// Task is one line of a todo.txt file. Priority goes from 'A', the highest,
+// to 'Z'; 0 means none. Text is the description without the projects,
+// contexts and key:value tags, which are kept apart without their prefix.
+type Task struct {
+	Done      bool
+	Priority  byte
+	Completed *time.Time
+	Created   *time.Time
+	Text      string
+	Projects  []string
+	Contexts  []string
+	Tags      map[string]string
+}
+
+// Parse reads one line. Every line is a valid task, so Parse does not fail;
+// a word only counts as a project, context or tag when it has a name after
+// its prefix. The priority of a completed task is read from its pri tag.
+func Parse(line string) Task {
+	var task Task
+	rest := strings.TrimSpace(line)
+	if after, ok := strings.CutPrefix(rest, "x "); ok {
+		task.Done = true
+		rest = strings.TrimLeft(after, " ")
+	}
+	if len(rest) >= 4 && rest[0] == '(' && isPriority(rest[1]) && rest[2] == ')' && rest[3] == ' ' {
+		task.Priority = rest[1]
+		rest = strings.TrimLeft(rest[4:], " ")
+	}
+	if date, after, ok := cutDate(rest); ok {
+		rest = after
+		if !task.Done {
+			task.Created = &date
+		} else {
+			task.Completed = &date
+			if created, after, ok := cutDate(rest); ok {
+				task.Created = &created
+				rest = after
+			}
+		}
+	}
+
+	var text []string
+	for _, word := range strings.Fields(rest) {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			task.Projects = append(task.Projects, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			task.Contexts = append(task.Contexts, word[1:])
+		default:
+			key, value, ok := cutTag(word)
+			if !ok {
+				text = append(text, word)
+				continue
+			}
+			if task.Tags == nil {
+				task.Tags = map[string]string{}
+			}
+			task.Tags[key] = value
+		}
+	}
+	task.Text = strings.Join(text, " ")
+
+	if pri := task.Tags[priorityKey]; task.Done && task.Priority == 0 && len(pri) == 1 && isPriority(pri[0]) {
+		task.Priority = pri[0]
+		delete(task.Tags, priorityKey)
+	}
+	return task
+}
+
+// String formats the task as one line, with the tags sorted by key. The
+// creation date of a completed task is only written along with its
+// completion date, since a lone date after x reads as the completion date.
+// Whitespace inside projects, contexts and tags becomes an underscore.
+func (t Task) String() string {
+	var b strings.Builder
+	if t.Done {
+		b.WriteString("x ")
+	} else if t.Priority != 0 {
+		b.WriteString("(" + string(t.Priority) + ") ")
+	}
+	if t.Done && t.Completed != nil {
+		b.WriteString(t.Completed.Format(dateLayout) + " ")
+	}
+	if t.Created != nil && (!t.Done || t.Completed != nil) {
+		b.WriteString(t.Created.Format(dateLayout) + " ")
+	}
+	b.WriteString(strings.Join(strings.Fields(t.Text), " "))
+
+	for _, project := range t.Projects {
+		b.WriteString(" +" + word(project))
+	}
+	for _, context := range t.Contexts {
+		b.WriteString(" @" + word(context))
+	}
+	keys := make([]string, 0, len(t.Tags))
+	for key := range t.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + word(key) + ":" + word(t.Tags[key]))
+	}
+	if t.Done && t.Priority != 0 {
+		b.WriteString(" " + priorityKey + ":" + string(t.Priority))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Writer writes tasks one per line.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Write(task Task) error {
+	_, err := io.WriteString(w.w, task.String()+"\n")
+	return err
+}
+
+// Decode reads every task of a todo.txt file, skipping blank lines. It only
+// fails when r does, or on a line longer than 64 KiB.
+func Decode(r io.Reader) ([]Task, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+	var tasks []Task
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tasks = append(tasks, Parse(line))
+	}
+	return tasks, scanner.Err()
+}
+
+func isPriority(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+// cutDate cuts a leading YYYY-MM-DD word off s.
+func cutDate(s string) (time.Time, string, bool) {
+	if len(s) < len(dateLayout) || (len(s) > len(dateLayout) && s[len(dateLayout)] != ' ') {
+		return time.Time{}, s, false
+	}
+	date, err := time.Parse(dateLayout, s[:len(dateLayout)])
+	if err != nil {
+		return time.Time{}, s, false
+	}
+	return date, strings.TrimLeft(s[len(dateLayout):], " "), true
+}
+
+// cutTag splits a key:value word. The key must start with a letter, the
+// value must not start with a slash and neither may hold another colon, so
+// that times and URLs stay text.
+func cutTag(word string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(word, ":")
+	if !ok || key == "" || value == "" || strings.Contains(value, ":") || value[0] == '/' {
+		return "", "", false
+	}
+	if first, _ := utf8.DecodeRuneInString(key); !unicode.IsLetter(first) {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+func word(s string) string {
+	return strings.Join(strings.Fields(s), "_")
+}
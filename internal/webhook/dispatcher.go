@@ -0,0 +1,235 @@
+// Package webhook sends the deliveries queued by the service to their
+// webhooks, signing each request and retrying failures with exponential
+// backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"task-api-huma-mongo/internal/service"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	// maxErrorBody bounds how much of a failed response is kept as the
+	// error of the attempt.
+	maxErrorBody = 512
+)
+
+// reservedPrefixes are ranges that are not reachable on the internet but
+// that netip does not classify as private: "this network" and the shared
+// address space of carrier-grade NAT, where some clouds serve metadata.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// Config tunes a Dispatcher. Attempt n of a delivery that keeps failing
+// is retried after BaseBackoff * 2^(n-1), capped at MaxBackoff, until
+// MaxAttempts attempts were made. Unless AllowPrivateTargets is set,
+// webhooks can only reach public addresses.
+type Config struct {
+	Workers             int
+	PollInterval        time.Duration
+	Timeout             time.Duration
+	MaxAttempts         int
+	BaseBackoff         time.Duration
+	MaxBackoff          time.Duration
+	AllowPrivateTargets bool
+}
+
+// Dispatcher polls the service for due deliveries and sends them. Several
+// dispatchers, in one process or many, can share the same deliveries.
+type Dispatcher struct {
+	svc    *service.Service
+	client *http.Client
+	cfg    Config
+}
+
+func NewDispatcher(svc *service.Service, cfg Config) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateTargets {
+		dialer.Control = refusePrivate
+	}
+	// No proxy: the address checked must be the one the request goes to.
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &Dispatcher{
+		svc:    svc,
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		cfg:    cfg,
+	}
+}
+
+// refusePrivate keeps webhooks from reaching the network the server runs
+// in: loopback, private, link-local (cloud metadata at 169.254.169.254
+// included), multicast and reserved addresses are refused. It runs as the
+// Control of the dialer, on the address actually dialed, so neither a name
+// resolving to such an address nor a redirect gets around it.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("webhook target %s is not a public address", ip)
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("webhook target %s is not a public address", ip)
+		}
+	}
+	return nil
+}
+
+// Run sends deliveries with cfg.Workers workers until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range max(d.cfg.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		for d.deliverNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNext sends the next due delivery and reports whether there was
+// one.
+func (d *Dispatcher) deliverNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	// The lease outlasts the request, so a delivery is only taken again
+	// when this dispatcher died before recording the attempt.
+	delivery, webhook, err := d.svc.ClaimDelivery(ctx, 2*d.cfg.Timeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("webhook claim failed", "err", err)
+		}
+		return false
+	}
+	if delivery == nil {
+		return false
+	}
+
+	attempt := d.send(ctx, delivery, webhook)
+	if ctx.Err() != nil {
+		// Shutting down: the lease expires and the delivery is retried.
+		return false
+	}
+	if err := d.svc.RecordAttempt(context.WithoutCancel(ctx), delivery, attempt); err != nil {
+		slog.Error("webhook attempt not recorded", "err", err, "delivery_id", delivery.ID)
+	}
+	return true
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *service.Delivery, webhook *service.Webhook) service.DeliveryAttempt {
+	attempt := service.DeliveryAttempt{At: time.Now().UTC()}
+	status, err := d.post(ctx, delivery, webhook)
+	attempt.ResponseStatus = status
+	if err == nil {
+		attempt.Status = service.DeliverySucceeded
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+	attempts := delivery.Attempts + 1
+	if attempts >= d.cfg.MaxAttempts {
+		attempt.Status = service.DeliveryFailed
+		return attempt
+	}
+	next := attempt.At.Add(d.backoff(attempts))
+	attempt.Status = service.DeliveryPending
+	attempt.NextAttemptAt = &next
+	return attempt
+}
+
+// post sends the event of delivery and returns the response status. Any
+// status but 2xx is an error.
+func (d *Dispatcher) post(ctx context.Context, delivery *service.Delivery, webhook *service.Webhook) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-api-webhooks/1.0")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
+
+// Sign returns the value of SignatureHeader for body: "sha256=" followed
+// by the hex HMAC-SHA256 of body keyed with secret. Receivers recompute it
+// over the raw request body and compare it with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}